@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// breakCheckInterval is how often the clock looks for a break started or
+// ended from the command line
+const breakCheckInterval = 30 * time.Second
+
+const breakUsage = "Usage: cclock break start [DATE] | end | status\n"
+
+// runBreak runs the break subcommands
+func runBreak(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, breakUsage)
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("break "+args[0], flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "%s\nStart a tolerance break today or on DATE, e.g. 2025-01-31, end it, or show how it's going.\n", breakUsage)
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	now := time.Now()
+	current, err := config.LoadBreak()
+	if err != nil {
+		return fmt.Errorf("Error loading break: %w", err)
+	}
+
+	switch {
+	case args[0] == "start" && flags.NArg() <= 1:
+		if current != nil {
+			return fmt.Errorf("a break is already going since %s", current.Start.Format(time.DateOnly))
+		}
+		start := dayStart(now)
+		if flags.NArg() == 1 {
+			start, err = time.ParseInLocation(time.DateOnly, flags.Arg(0), time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid date %q, use e.g. 2025-01-31", flags.Arg(0))
+			}
+			if start.After(now) {
+				return errors.New("a break can't start in the future")
+			}
+		}
+		if err := config.StartBreak(start); err != nil {
+			return fmt.Errorf("Error saving break: %w", err)
+		}
+		fmt.Printf("Day %d of break\n", breakDay(start, now))
+	case args[0] == "end" && flags.NArg() == 0:
+		if current == nil {
+			return errors.New("no break is going")
+		}
+		if err := config.EndBreak(now, "completed"); err != nil {
+			return fmt.Errorf("Error saving break: %w", err)
+		}
+		fmt.Printf("Break completed after %s\n", formatDays(breakDay(current.Start, now)))
+	case args[0] == "status" && flags.NArg() == 0:
+		if current != nil {
+			fmt.Printf("Day %d of break, since %s\n", breakDay(current.Start, now), current.Start.Format(time.DateOnly))
+		} else {
+			fmt.Println("No break")
+		}
+		history, err := config.LoadHistory()
+		if err != nil {
+			return fmt.Errorf("Error loading history: %w", err)
+		}
+		fmt.Printf("Longest streak: %s\n", formatDays(longestStreak(history, current, now)))
+		breaks, err := config.LoadBreaks()
+		if err != nil {
+			return fmt.Errorf("Error loading breaks: %w", err)
+		}
+		for _, b := range breaks {
+			fmt.Printf("%s to %s: %s after %s\n", b.Start.Format(time.DateOnly), b.End.Format(time.DateOnly), b.Outcome, formatDays(breakDay(b.Start, b.End)))
+		}
+	default:
+		fmt.Fprint(os.Stderr, breakUsage)
+		return errUsage
+	}
+	return nil
+}
+
+// breakDay returns which day of a break that started on start it is at now,
+// counting from 1
+func breakDay(start, now time.Time) int {
+	// Rounded, as days around a daylight saving change aren't 24 hours long
+	return int(math.Round(dayStart(now).Sub(dayStart(start)).Hours()/24)) + 1
+}
+
+// formatDays formats a number of days, e.g. "1 day" or "12 days"
+func formatDays(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// longestStreak returns the most whole days that went by without a session,
+// between two sessions of the history or since the last one. current is the
+// break going on, if any: the days since it started count even when the
+// history doesn't go back that far.
+func longestStreak(history []config.SessionRecord, current *config.Break, now time.Time) int {
+	longest := time.Duration(0)
+	if current != nil {
+		longest = now.Sub(current.Start)
+	}
+	if len(history) == 0 {
+		return int(max(longest, 0) / (24 * time.Hour))
+	}
+	// Sessions are logged when they end, so they can be out of start order
+	history = slices.Clone(history)
+	slices.SortFunc(history, func(a, b config.SessionRecord) int { return a.Start.Compare(b.Start) })
+	lastEnd := history[0].End
+	for _, record := range history[1:] {
+		longest = max(longest, record.Start.Sub(lastEnd))
+		if record.End.After(lastEnd) {
+			lastEnd = record.End
+		}
+	}
+	longest = max(longest, now.Sub(lastEnd))
+	return int(longest / (24 * time.Hour))
+}
+
+// breakBroken ends the break that's going on as broken if a session was
+// started between before and after
+func breakBroken(before, after timerEngine, now time.Time) error {
+	if !sessionStarted(before, after) {
+		return nil
+	}
+	return config.EndBreak(now, "broken")
+}
+
+// refreshBreak loads the break that's going on again, for the clock to show
+func (m model) refreshBreak() model {
+	m.onBreak, _ = config.LoadBreak()
+	m.breakChecked = time.Now()
+	m.streak = 0
+	if m.onBreak != nil {
+		if history, err := config.LoadHistory(); err == nil {
+			m.streak = longestStreak(history, m.onBreak, m.breakChecked)
+		}
+	}
+	return m
+}
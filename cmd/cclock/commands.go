@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/control"
+)
+
+const usage = `Usage: cclock [options]
+       cclock COMMAND [arguments]
+
+Without a command cclock opens the clock and timer in the terminal.
+
+Commands:
+  daemon [options]   Run the timer without the clock, driving the status bar files only
+  start [profile]    Start a profile, by name or number, or the default profile
+  stop               Stop the running timer
+  toggle [profile]   Stop the running timer, or start a profile when none runs
+  pause              Pause the running timer, or resume it when paused
+  resume             Resume the paused timer
+  next               Skip to the next phase
+  back               Go back to the start of the previous phase
+  extend DURATION    Add time to the current phase, e.g. 30s or 1m
+  status [--json]    Show the state of the timer
+  subscribe [--json] Print timer events as they happen
+  swiftbar           Print the timer as a SwiftBar or xbar plugin
+  tmux               Print the timer for the tmux status line
+  history export     Print the session history as CSV, JSON or iCalendar
+  break start [DATE] Start a tolerance break, today or on DATE
+  break end          End the tolerance break
+  break status       Show the tolerance break and the longest streak
+  help               Show this help
+
+All commands but daemon, history, break and help talk to the running clock or daemon.
+`
+
+// Exit codes of the commands
+const (
+	exitOK         = 0
+	exitError      = 1
+	exitUsage      = 2
+	exitNotRunning = 3
+)
+
+// runCommand runs a cclock subcommand and returns its exit code
+func runCommand(name string, args []string) int {
+	var err error
+	switch name {
+	case "daemon":
+		err = runDaemon(args)
+	case "start", "stop", "toggle", "pause", "resume", "next", "back", "extend", "status":
+		err = runClient(name, args)
+	case "subscribe":
+		err = runSubscribe(args)
+	case "swiftbar":
+		err = runSwiftBar(args)
+	case "tmux":
+		err = runTmux(args)
+	case "history":
+		err = runHistory(args)
+	case "break":
+		err = runBreak(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", name, usage)
+		return exitUsage
+	}
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, control.ErrNotRunning):
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitNotRunning
+	default:
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+}
+
+// errUsage is returned by commands that were called with the wrong arguments
+// after they've printed their usage
+var errUsage = errors.New("usage")
+
+// runClient sends a command to the running instance and prints the reply
+func runClient(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the status as JSON")
+	argument := map[string]string{"start": " [profile]", "toggle": " [profile]", "extend": " DURATION"}[name]
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cclock %s [--json]%s\n", name, argument)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	req := control.Request{Command: name}
+	switch {
+	case flags.NArg() > 1, flags.NArg() == 1 && argument == "", flags.NArg() == 0 && name == "extend":
+		flags.Usage()
+		return errUsage
+	case name == "extend":
+		extra, err := parseExtend(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		req.Seconds = int(extra.Seconds())
+	case flags.NArg() == 1:
+		req.Profile = flags.Arg(0)
+	}
+
+	resp, err := control.Send(req)
+	if err != nil {
+		return err
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+
+	if *asJSON {
+		data, err := json.Marshal(resp.Status)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else if name == "status" && resp.Status != nil {
+		fmt.Println(formatStatus(*resp.Status))
+	}
+	return nil
+}
+
+// parseExtend reads the time to extend a phase by. Plain numbers are seconds.
+func parseExtend(arg string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(arg); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	extra, err := time.ParseDuration(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, use e.g. 30s or 1m", arg)
+	}
+	return extra, nil
+}
+
+// runSubscribe prints the running instance's events until it exits
+func runSubscribe(args []string) error {
+	flags := flag.NewFlagSet("subscribe", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print each event as a line of JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cclock subscribe [--json]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	return control.Subscribe(func(event control.Event) error {
+		if *asJSON {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			fmt.Printf("%s: %s\n", event.Type, formatStatus(event.Status))
+		}
+		return nil
+	})
+}
+
+// formatStatus describes a status in one line, e.g.
+// "Timer 1: running 3:12 of 10:00, phase 2/3 at 375°"
+func formatStatus(status control.Status) string {
+	if status.State == "idle" {
+		return "idle"
+	}
+	return fmt.Sprintf("%s: %s %d:%02d of %d:%02d, phase %d/%d at %d°",
+		status.Profile, status.State,
+		status.ElapsedSeconds/60, status.ElapsedSeconds%60,
+		status.TotalSeconds/60, status.TotalSeconds%60,
+		status.Phase, status.PhaseCount, status.Temp)
+}
@@ -9,40 +9,32 @@ import (
 	util "github.com/unquenchedservant/ChillClock/utilities"
 )
 
+// fieldName is the profile name row at the top of every config page
+const fieldName configField = 0
+
 func (m model) handleConfigInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editingField && m.selectedField == fieldName && (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) {
+		// Names can contain letters that otherwise navigate, like j and k
+		m.inputBuffer += string(msg.Runes)
+		return m, nil
+	}
 	if m.editingField {
 		switch msg.String() {
 		case "enter", "esc":
-			if m.inputBuffer == ""{
-				m.setFieldValue(m.previousValue)
-			} else if val := m.parseInput(); val >= 0 {
-				m.setFieldValue(val)
-				config.SaveConfig(m.config)
-			} else {
-				m.setFieldValue(m.previousValue)
-			}
-			m.editingField = false
-			m.inputBuffer = ""
-		case "backspace": 
+			m.saveAndExitField()
+		case "backspace":
 			if len(m.inputBuffer) > 0 {
-				m.inputBuffer = m.inputBuffer[:len(m.inputBuffer) - 1]
+				runes := []rune(m.inputBuffer)
+				m.inputBuffer = string(runes[:len(runes)-1])
 			}
 		case "up", "k":
 			m.saveAndExitField()
-			minField := fieldPhase1DurationT1
-			if m.configPage == CFG_PAGE_2 {
-				minField = fieldPhase1DurationT2
-			}
-			if m.selectedField > minField {
+			if m.selectedField > 0 {
 				m.selectedField--
 			}
 		case "down", "j":
 			m.saveAndExitField()
-			maxField := fieldPhase3TempT1
-			if m.configPage == CFG_PAGE_2 {
-				maxField = fieldPhase3TempT2
-			}
-			if m.selectedField < maxField {
+			if m.selectedField < m.maxField() {
 				m.selectedField++
 			}
 		default:
@@ -52,34 +44,64 @@ func (m model) handleConfigInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	} else {
 		switch msg.String() {
-		case "esc", "q", "?": 
+		case "esc", "q", "?":
 			m.mode = viewClock
 		case "up", "k":
-			minField := fieldPhase1DurationT1
-			if m.configPage == CFG_PAGE_2 {
-				minField = fieldPhase1DurationT2
-			}
-			if m.selectedField > minField {
+			if m.selectedField > 0 {
 				m.selectedField--
 			}
 		case "left", "h":
-			if m.configPage == CFG_PAGE_2 {
-				m.selectedField -= 6
+			if m.configPage > 0 {
+				m.configPage--
 			}
-			m.configPage = CFG_PAGE_1
+			m.selectedField = min(m.selectedField, m.maxField())
 		case "right", "l":
-			if m.configPage == CFG_PAGE_1 {
-				m.selectedField += 6
+			if m.configPage < len(m.config.Profiles)-1 {
+				m.configPage++
 			}
-			m.configPage = CFG_PAGE_2
+			m.selectedField = min(m.selectedField, m.maxField())
 		case "down", "j":
-			maxField := fieldPhase3TempT1
-			if m.configPage == CFG_PAGE_2 {
-				maxField = fieldPhase3TempT2
-			}
-			if m.selectedField < maxField {
+			if m.selectedField < m.maxField() {
 				m.selectedField++
 			}
+		case "a":
+			// New phases start as a copy of the last one
+			phases := m.pagePhases()
+			newPhase := config.Phase{DurationMinutes: 1, Temp: 350}
+			if len(phases) > 0 {
+				newPhase = phases[len(phases)-1]
+			}
+			m.setPagePhases(append(phases[:len(phases):len(phases)], newPhase))
+			config.SaveConfig(m.config)
+		case "x":
+			phases := m.pagePhases()
+			if index, _ := m.fieldPhase(m.selectedField); m.selectedField != fieldName && len(phases) > 1 {
+				phases = append(phases[:index:index], phases[index+1:]...)
+				m.setPagePhases(phases)
+				m.selectedField = min(m.selectedField, m.maxField())
+				config.SaveConfig(m.config)
+			}
+		case "n":
+			// New profiles start as a copy of the one on screen
+			profile := m.profile(m.configPage)
+			profile.Name = m.newProfileName()
+			profile.Phases = append([]config.Phase(nil), profile.Phases...)
+			m.config.Profiles = append(m.config.Profiles[:len(m.config.Profiles):len(m.config.Profiles)], profile)
+			m.configPage = len(m.config.Profiles) - 1
+			m.selectedField = fieldName
+			config.SaveConfig(m.config)
+		case "X":
+			if len(m.config.Profiles) > 1 {
+				profiles := m.config.Profiles
+				m.config.Profiles = append(profiles[:m.configPage:m.configPage], profiles[m.configPage+1:]...)
+				if m.config.ProfileIndex(m.config.DefaultProfile) < 0 {
+					m.config.DefaultProfile = m.config.Profiles[0].Name
+				}
+				m.timerDefault = m.config.ProfileIndex(m.config.DefaultProfile)
+				m.configPage = min(m.configPage, len(m.config.Profiles)-1)
+				m.selectedField = min(m.selectedField, m.maxField())
+				config.SaveConfig(m.config)
+			}
 		case "enter", " ":
 			m.previousValue = m.getFieldValue()
 			m.editingField = true
@@ -90,7 +112,9 @@ func (m model) handleConfigInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) saveAndExitField() {
-	if m.inputBuffer == "" {
+	if m.selectedField == fieldName {
+		m.setProfileName(strings.TrimSpace(m.inputBuffer))
+	} else if m.inputBuffer == "" {
 		m.setFieldValue(m.previousValue)
 	} else if val := m.parseInput(); val >= 0 {
 		m.setFieldValue(val)
@@ -102,63 +126,84 @@ func (m *model) saveAndExitField() {
 	m.inputBuffer = ""
 }
 
+// setProfileName renames the profile on the current config page. Blank names
+// and names already used by another profile are ignored.
+func (m *model) setProfileName(name string) {
+	if name == "" || m.config.ProfileIndex(name) >= 0 {
+		return
+	}
+	profiles := append([]config.Profile(nil), m.config.Profiles...)
+	if profiles[m.configPage].Name == m.config.DefaultProfile {
+		m.config.DefaultProfile = name
+	}
+	profiles[m.configPage].Name = name
+	m.config.Profiles = profiles
+	config.SaveConfig(m.config)
+}
+
+// newProfileName returns the first "Profile N" name that isn't taken yet
+func (m model) newProfileName() string {
+	for i := len(m.config.Profiles) + 1; ; i++ {
+		name := fmt.Sprintf("Profile %d", i)
+		if m.config.ProfileIndex(name) < 0 {
+			return name
+		}
+	}
+}
+
+// pagePhases returns the phases of the profile shown on the current config page
+func (m model) pagePhases() []config.Phase {
+	return m.phases(m.configPage)
+}
+
+func (m *model) setPagePhases(phases []config.Phase) {
+	profiles := append([]config.Profile(nil), m.config.Profiles...)
+	profiles[m.configPage].Phases = phases
+	m.config.Profiles = profiles
+}
+
+func (m model) maxField() configField {
+	return configField(2 * len(m.pagePhases()))
+}
+
+// fieldPhase returns the index of the phase a config row belongs to and
+// whether the row is that phase's temperature rather than its duration
+func (m model) fieldPhase(field configField) (int, bool) {
+	count := len(m.pagePhases())
+	if int(field) > count {
+		return int(field) - count - 1, true
+	}
+	return int(field) - 1, false
+}
+
 func (m model) getFieldValue() int {
-	switch m.selectedField {
-	case fieldPhase1DurationT1:
-		return m.config.Timer.Phase1Duration_Timer1
-	case fieldPhase2DurationT1:
-		return m.config.Timer.Phase2Duration_Timer1
-	case fieldPhase3DurationT1:
-		return m.config.Timer.Phase3Duration_Timer1
-	case fieldPhase1TempT1:
-		return m.config.Timer.Phase1Temp_Timer1
-	case fieldPhase2TempT1:
-		return m.config.Timer.Phase2Temp_Timer1
-	case fieldPhase3TempT1:
-		return m.config.Timer.Phase3Temp_Timer1
-	case fieldPhase1DurationT2:
-		return m.config.Timer.Phase1Duration_Timer2
-	case fieldPhase2DurationT2:
-		return m.config.Timer.Phase2Duration_Timer2
-	case fieldPhase3DurationT2:
-		return m.config.Timer.Phase3Duration_Timer2
-	case fieldPhase1TempT2:
-		return m.config.Timer.Phase1Temp_Timer2
-	case fieldPhase2TempT2:
-		return m.config.Timer.Phase2Temp_Timer2
-	case fieldPhase3TempT2:
-		return m.config.Timer.Phase3Temp_Timer2
+	return m.fieldValue(m.selectedField)
+}
+
+func (m model) fieldValue(field configField) int {
+	index, isTemp := m.fieldPhase(field)
+	phases := m.pagePhases()
+	if index < 0 || index >= len(phases) {
+		return 0
+	}
+	if isTemp {
+		return phases[index].Temp
 	}
-	return 0
+	return phases[index].DurationMinutes
 }
 
 func (m *model) setFieldValue(val int) {
-	switch m.selectedField {
-	case fieldPhase1DurationT1:
-		m.config.Timer.Phase1Duration_Timer1 = val
-	case fieldPhase2DurationT1:
-		m.config.Timer.Phase2Duration_Timer1 = val
-	case fieldPhase3DurationT1:
-		m.config.Timer.Phase3Duration_Timer1 = val
-	case fieldPhase1TempT1:
-		m.config.Timer.Phase1Temp_Timer1 = val
-	case fieldPhase2TempT1:
-		m.config.Timer.Phase2Temp_Timer1 = val
-	case fieldPhase3TempT1:
-		m.config.Timer.Phase3Temp_Timer1 = val
-	case fieldPhase1DurationT2:
-		m.config.Timer.Phase1Duration_Timer2 = val
-	case fieldPhase2DurationT2:
-		m.config.Timer.Phase2Duration_Timer2 = val
-	case fieldPhase3DurationT2:
-		m.config.Timer.Phase3Duration_Timer2 = val
-	case fieldPhase1TempT2:
-		m.config.Timer.Phase1Temp_Timer2 = val
-	case fieldPhase2TempT2:
-		m.config.Timer.Phase2Temp_Timer2 = val
-	case fieldPhase3TempT2:
-		m.config.Timer.Phase3Temp_Timer2 = val
+	index, isTemp := m.fieldPhase(m.selectedField)
+	phases := append([]config.Phase(nil), m.pagePhases()...)
+	if index < 0 || index >= len(phases) {
+		return
 	}
+	if isTemp {
+		phases[index].Temp = val
+	} else {
+		phases[index].DurationMinutes = val
+	}
+	m.setPagePhases(phases)
 }
 
 func (m model) parseInput() int {
@@ -171,51 +216,36 @@ func (m model) parseInput() int {
 
 func (m model) renderConfigView() string {
 	var output strings.Builder
-	fields := []struct {
-		name string 
+	type configRow struct {
+		name  string
 		field configField
-		unit string
-	}{}
-	minField := fieldPhase1DurationT1
-	maxField := fieldPhase3TempT1
-	if m.configPage == CFG_PAGE_2 {
-		minField = fieldPhase1DurationT2
-		maxField = fieldPhase3TempT2
+		unit  string
+	}
+	minField := fieldName
+	maxField := m.maxField()
+	profile := m.profile(m.configPage)
+	title := fmt.Sprintf("    %s Configuration (%d/%d)", profile.Name, m.configPage+1, len(m.config.Profiles))
+	if m.configPage == m.timerDefault {
+		title += " - default"
 	}
 	output.WriteString("\n")
-	output.WriteString(util.CenterText(util.GetYellowStyle().Bold(true).Render(fmt.Sprintf("    Timer %d Configuration", m.configPage+1)), m.width))
+	output.WriteString(util.CenterText(util.GetYellowStyle().Bold(true).Render(title), m.width))
 	output.WriteString("\n\n")
-	if m.configPage == CFG_PAGE_1 {
-		fields = []struct {
-			name string
-			field configField
-			unit string
-		}{
-			{"Phase 1 Duration", fieldPhase1DurationT1, " minutes"},
-			{"Phase 2 Duration", fieldPhase2DurationT1, " minutes"},
-			{"Phase 3 Duration", fieldPhase3DurationT1, " minutes"},
-			{"Phase 1 Temperature", fieldPhase1TempT1, "°"},
-			{"Phase 2 Temperature", fieldPhase2TempT1, "°"},
-			{"Phase 3 Temperature", fieldPhase3TempT1, "°"},
-		}
-	} else {
-		fields = []struct {
-			name string
-			field configField
-			unit string
-		}{
-			{"Phase 1 Duration", fieldPhase1DurationT2, " minutes"},
-			{"Phase 2 Duration", fieldPhase2DurationT2, " minutes"},
-			{"Phase 3 Duration", fieldPhase3DurationT2, " minutes"},
-			{"Phase 1 Temperature", fieldPhase1TempT2, "°"},
-			{"Phase 2 Temperature", fieldPhase2TempT2, "°"},
-			{"Phase 3 Temperature", fieldPhase3TempT2, "°"},
-		}
+	fields := []configRow{{"Name", fieldName, ""}}
+	phaseCount := len(profile.Phases)
+	for i := 0; i < phaseCount; i++ {
+		fields = append(fields, configRow{fmt.Sprintf("Phase %d Duration", i+1), configField(i + 1), " minutes"})
+	}
+	for i := 0; i < phaseCount; i++ {
+		fields = append(fields, configRow{fmt.Sprintf("Phase %d Temperature", i+1), configField(phaseCount + i + 1), "°"})
 	}
 
 	for _, f := range fields {
 		var line string
-		value := m.getFieldValue()
+		value := fmt.Sprint(m.fieldValue(f.field))
+		if f.field == fieldName {
+			value = profile.Name
+		}
 		if f.field == m.selectedField {
 			if m.editingField {
 				displayValue := m.inputBuffer
@@ -225,37 +255,11 @@ func (m model) renderConfigView() string {
 				line = fmt.Sprintf("  ▶ %s: %s%s", f.name, displayValue, f.unit)
 				line = util.GetEditingStyle().Render(line)
 			} else {
-				line = fmt.Sprintf("  ▶ %s: %d%s", f.name, value, f.unit)
-                line = util.GetGreenStyle().Bold(true).Render(line)
+				line = fmt.Sprintf("  ▶ %s: %s%s", f.name, value, f.unit)
+				line = util.GetGreenStyle().Bold(true).Render(line)
 			}
 		} else {
-			switch f.field{
-			case fieldPhase1DurationT1:
-				value = m.config.Timer.Phase1Duration_Timer1
-			case fieldPhase2DurationT1:
-				value = m.config.Timer.Phase2Duration_Timer1
-			case fieldPhase3DurationT1:
-				value = m.config.Timer.Phase3Duration_Timer1
-			case fieldPhase1TempT1:
-				value = m.config.Timer.Phase1Temp_Timer1
-			case fieldPhase2TempT1:
-				value = m.config.Timer.Phase2Temp_Timer1
-			case fieldPhase3TempT1:
-				value = m.config.Timer.Phase3Temp_Timer1
-			case fieldPhase1DurationT2:
-				value = m.config.Timer.Phase1Duration_Timer2
-			case fieldPhase2DurationT2:
-				value = m.config.Timer.Phase2Duration_Timer2
-			case fieldPhase3DurationT2:
-				value = m.config.Timer.Phase3Duration_Timer2
-			case fieldPhase1TempT2:
-				value = m.config.Timer.Phase1Temp_Timer2
-			case fieldPhase2TempT2:
-				value = m.config.Timer.Phase2Temp_Timer2
-			case fieldPhase3TempT2:
-				value = m.config.Timer.Phase3Temp_Timer2
-			}
-			line = fmt.Sprintf("    %s: %d%s", f.name, value, f.unit)
+			line = fmt.Sprintf("    %s: %s%s", f.name, value, f.unit)
 			line = util.GetNormalStyle().Render(line)
 		}
 
@@ -266,10 +270,14 @@ func (m model) renderConfigView() string {
 	output.WriteString("\n")
 	navigate_page := ""
 	up_down := ""
-	if m.configPage == CFG_PAGE_1 {
-		navigate_page = "→: Next Timer | "
-	}else if m.configPage == CFG_PAGE_2 {
-		navigate_page = "←: Prv. Timer | "
+	if len(m.config.Profiles) > 1 {
+		if m.configPage == 0 {
+			navigate_page = "→: Next Profile | "
+		} else if m.configPage == len(m.config.Profiles)-1 {
+			navigate_page = "←: Prv. Profile | "
+		} else {
+			navigate_page = "←/→: Profiles | "
+		}
 	}
 	if m.selectedField == minField {
 		up_down = "↓: Navigate | "
@@ -279,14 +287,18 @@ func (m model) renderConfigView() string {
 		up_down = "↑/↓: Navigate | "
 	}
 	helpText := fmt.Sprintf("%s%sEnter: Edit | Esc/q/?: Exit", navigate_page, up_down)
-    if m.editingField {
-        helpText = "Type value | Enter: Save | Esc: Cancel"
-    }
-	
+	helpText2 := "a/x: Add/Remove Phase | n/X: New/Delete Profile"
+	if m.editingField {
+		helpText = "Type value | Enter: Save | Esc: Cancel"
+		helpText2 = ""
+	}
+
 	output.WriteString(util.CenterText(util.GetNormalStyle().Render(helpText), m.width))
 	output.WriteString("\n")
+	output.WriteString(util.CenterText(util.GetNormalStyle().Render(helpText2), m.width))
+	output.WriteString("\n")
 	versionText := version
 	output.WriteString(util.CenterText(util.GetNormalStyle().Render(versionText), m.width))
 
 	return output.String()
-}
\ No newline at end of file
+}
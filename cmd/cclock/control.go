@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+	"github.com/unquenchedservant/ChillClock/control"
+)
+
+// controlCall is a control request waiting for the timer to answer it. The
+// TUI receives it as a message, the daemon over a channel.
+type controlCall struct {
+	request control.Request
+	reply   chan control.Response
+}
+
+// forwardRequests returns a control handler that hands each request to send
+// and waits for the timer to reply
+func forwardRequests(send func(controlCall)) control.Handler {
+	return func(req control.Request) control.Response {
+		call := controlCall{request: req, reply: make(chan control.Response, 1)}
+		send(call)
+		select {
+		case resp := <-call.reply:
+			return resp
+		case <-time.After(5 * time.Second):
+			return control.Response{Error: "timed out waiting for the timer"}
+		}
+	}
+}
+
+// handleRequest carries out a control request on the timer. It returns the
+// reply and whether the request moved the timer to another phase.
+func handleRequest(timer *timerEngine, cfg config.Config, req control.Request, now time.Time) (control.Response, bool) {
+	phaseChanged := false
+	notRunning := control.Response{Error: "no timer is running"}
+	switch req.Command {
+	case "status":
+	case "start", "toggle":
+		if timer.running {
+			if req.Command == "toggle" {
+				timer.stop()
+				break
+			}
+			return control.Response{Error: fmt.Sprintf("%s is already running", timer.profile.Name)}, false
+		}
+		profile, err := findProfile(cfg, req.Profile)
+		if err != nil {
+			return control.Response{Error: err.Error()}, false
+		}
+		if reason := refusal(cfg.Limits, now); reason != "" {
+			return control.Response{Error: reason}, false
+		}
+		phaseChanged = timer.begin(profile, now)
+	case "stop":
+		if !timer.running {
+			return notRunning, false
+		}
+		timer.stop()
+	case "pause", "resume":
+		if !timer.running {
+			return notRunning, false
+		}
+		// pause on its own toggles, so one key or click can do both
+		if req.Command == "pause" || timer.paused {
+			timer.togglePause(now)
+		}
+	case "skip", "next", "back":
+		if !timer.running {
+			return notRunning, false
+		}
+		steps := req.Steps
+		if req.Command == "back" {
+			steps = -1
+		} else if steps == 0 {
+			steps = 1
+		}
+		for ; steps > 0 && timer.running; steps-- {
+			phaseChanged = timer.skip(1, now) || phaseChanged
+		}
+		for ; steps < 0 && timer.running; steps++ {
+			phaseChanged = timer.skip(-1, now) || phaseChanged
+		}
+	case "extend":
+		if !timer.running {
+			return notRunning, false
+		}
+		if req.Seconds <= 0 {
+			return control.Response{Error: "extend needs a positive number of seconds"}, false
+		}
+		timer.extend(time.Duration(req.Seconds) * time.Second)
+	default:
+		return control.Response{Error: fmt.Sprintf("unknown command %q", req.Command)}, false
+	}
+
+	if req.Command != "status" {
+		timer.save()
+	}
+	status := timer.status()
+	return control.Response{OK: true, Status: &status}, phaseChanged
+}
+
+// timerEvents returns the events describing how the timer changed from
+// before to after, in the order they happened
+func timerEvents(before, after timerEngine) []control.Event {
+	var types []string
+	switch {
+	case sessionStarted(before, after):
+		types = append(types, "started")
+	case before.running && !after.running && after.phase == phaseCompleted:
+		types = append(types, "completed")
+	case before.running && !after.running:
+		types = append(types, "stopped")
+	}
+	if after.running {
+		if after.phase != before.phase && after.phase > phaseNotStarted {
+			types = append(types, "phase")
+		}
+		if before.running && !before.paused && after.paused {
+			types = append(types, "paused")
+		} else if before.paused && !after.paused {
+			types = append(types, "resumed")
+		}
+		if before.running && after.phase == before.phase && phaseStart(after.phaseDurations, after.phase+1) > phaseStart(before.phaseDurations, before.phase+1) {
+			types = append(types, "extended")
+		}
+	}
+
+	events := make([]control.Event, len(types))
+	for i, t := range types {
+		events[i] = control.Event{Type: t, Status: after.status()}
+	}
+	return events
+}
+
+// announce publishes timer events to control subscribers and, when it's
+// enabled, has tmux redraw its status line
+func announce(server *control.Server, cfg config.Config, events []control.Event) {
+	for _, event := range events {
+		server.Publish(event)
+	}
+	if cfg.TmuxRefresh && len(events) > 0 {
+		go refreshTmux()
+	}
+}
+
+// findProfile looks a profile up by name or by its position in the list,
+// counting from 1. An empty name means the default profile.
+func findProfile(cfg config.Config, name string) (config.Profile, error) {
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+	if index := cfg.ProfileIndex(name); index >= 0 {
+		return cfg.Profiles[index], nil
+	}
+	if number, err := strconv.Atoi(name); err == nil && number >= 1 && number <= len(cfg.Profiles) {
+		return cfg.Profiles[number-1], nil
+	}
+	if name == cfg.DefaultProfile && len(cfg.Profiles) > 0 {
+		return cfg.Profiles[0], nil
+	}
+	return config.Profile{}, fmt.Errorf("no profile named %q", name)
+}
+
+// status describes the timer for control clients
+func (e timerEngine) status() control.Status {
+	status := control.Status{State: "idle"}
+	switch {
+	case e.phase == phaseCompleted:
+		status.State = "completed"
+	case e.idle():
+		return status
+	case e.paused:
+		status.State = "paused"
+	default:
+		status.State = "running"
+	}
+
+	total := totalDuration(e.phaseDurations)
+	status.Profile = e.profile.Name
+	status.Phase = max(int(e.phase), 0)
+	status.PhaseCount = len(e.profile.Phases)
+	status.Temp = e.temp()
+	status.ElapsedSeconds = int(e.elapsed.Seconds())
+	status.TotalSeconds = int(total.Seconds())
+	status.RemainingSeconds = max(int((total - e.elapsed).Seconds()), 0)
+	return status
+}
+
+// serveHTTP serves the control API over HTTP on addr in the background, to
+// browsers on the given origins only
+func serveHTTP(addr string, origins []string, server *control.Server) (*http.Server, error) {
+	listener, err := control.ListenHTTP(addr)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening HTTP API: %w", err)
+	}
+	httpServer := &http.Server{Handler: server.HTTPHandler(origins)}
+	go httpServer.Serve(listener)
+	return httpServer, nil
+}
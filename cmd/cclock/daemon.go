@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/unquenchedservant/ChillClock/config"
+	"github.com/unquenchedservant/ChillClock/control"
+	util "github.com/unquenchedservant/ChillClock/utilities"
+)
+
+// runDaemon runs the timer without the TUI. It drives the same phase engine,
+// writes the status bar file, reacts to the click files and control commands
+// and sends the notifications until it's told to stop with SIGTERM or SIGINT. SIGHUP
+// reloads the config.
+func runDaemon(args []string) error {
+	flags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	opts := addRunFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cclock daemon [options]\n\nRun the timer in the background without the clock.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	files, err := setupStatusFiles(cfg, opts, true)
+	if err != nil {
+		return err
+	}
+	texts, err := parseTemplates(cfg.Templates)
+	if err != nil {
+		return err
+	}
+
+	calls := make(chan controlCall)
+	handler := forwardRequests(func(call controlCall) { calls <- call })
+	// Before anything else, so a second daemon leaves the saved session to
+	// the one already running
+	server, err := control.Listen(handler)
+	if err != nil {
+		return fmt.Errorf("Error opening control socket: %w", err)
+	}
+	defer server.Close()
+
+	desktop := util.NewDBusNotifier(notificationActionHandler(handler))
+	defer desktop.Close()
+	notifier, err := buildNotifier(cfg, desktop, nil)
+	if err != nil {
+		return err
+	}
+
+	var timer timerEngine
+	if state, err := config.LoadSession(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading saved session: %v\n", err)
+	} else if state != nil {
+		// There's nobody to ask, so a saved session is always picked up again
+		if index := cfg.ProfileIndex(state.Profile); index >= 0 {
+			changed, finished := timer.resume(*state, cfg.Profiles[index], time.Now())
+			timer.save()
+			if finished != nil {
+				if err := config.AppendHistory(*finished); err != nil {
+					fmt.Fprintf(os.Stderr, "Error saving session history: %v\n", err)
+				}
+			}
+			if changed {
+				notifyInBackground(texts.notification(timer), notifier)
+			}
+		} else {
+			config.ClearSession()
+		}
+	}
+
+	go server.Serve()
+
+	if opts.httpAddr != "" {
+		httpServer, err := serveHTTP(opts.httpAddr, cfg.HTTPOrigins, server)
+		if err != nil {
+			return err
+		}
+		defer httpServer.Close()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	clicks := make(chan tea.Msg)
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	files.watchTriggers(func(msg tea.Msg) {
+		select {
+		case clicks <- msg:
+		case <-stopWatching:
+		}
+	}, stopWatching)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		before := timer
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				newCfg, err := loadConfig()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					continue
+				}
+				newTexts, err := parseTemplates(newCfg.Templates)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					continue
+				}
+				newNotifier, err := buildNotifier(newCfg, desktop, nil)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					continue
+				}
+				cfg, texts, notifier = newCfg, newTexts, newNotifier
+				continue
+			}
+			// The session stays saved so it carries on with the next start
+			return files.writeTimerState(timerEngine{}, texts)
+		case call := <-calls:
+			resp, phaseChanged := handleRequest(&timer, cfg, call.request, time.Now())
+			call.reply <- resp
+			if phaseChanged {
+				notifyInBackground(texts.notification(timer), notifier)
+			}
+			files.writeTimerState(timer, texts)
+		case msg := <-clicks:
+			now := time.Now()
+			switch msg := msg.(type) {
+			case fileClickMsg:
+				if msg.offset >= len(cfg.Profiles) {
+					break
+				}
+				if timer.running {
+					timer.stop()
+				} else if reason := refusal(cfg.Limits, now); reason != "" {
+					fmt.Fprintf(os.Stderr, "Not starting: %s\n", reason)
+				} else {
+					defaultProfile := max(cfg.ProfileIndex(cfg.DefaultProfile), 0)
+					if timer.begin(cfg.Profiles[(defaultProfile+msg.offset)%len(cfg.Profiles)], now) {
+						notifyInBackground(texts.notification(timer), notifier)
+					}
+				}
+			case pauseClickMsg:
+				timer.togglePause(now)
+			}
+			timer.save()
+			files.writeTimerState(timer, texts)
+		case now := <-ticker.C:
+			if timer.tick(now) {
+				timer.save()
+				notifyInBackground(texts.notification(timer), notifier)
+			}
+			files.writeTimerState(timer, texts)
+		}
+
+		if err := recordHistory(before, timer, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving session history: %v\n", err)
+		}
+		if err := breakBroken(before, timer, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving break: %v\n", err)
+		}
+		announce(server, cfg, timerEvents(before, timer))
+	}
+}
+
+// notifyInBackground rings and notifies for a new phase without holding up
+// the daemon. There's nowhere to show a failure but the log.
+func notifyInBackground(n util.Notification, notifier util.NotifierChain) {
+	go func() {
+		if _, err := ding(n, notifier); err != nil {
+			fmt.Fprintf(os.Stderr, "Notification failed: %v\n", err)
+		}
+	}()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// timerEngine is the phase engine behind a timer session. The TUI and the
+// daemon both drive one of these and react to the phase changes it reports.
+type timerEngine struct {
+	profile        config.Profile // profile the session was started with
+	running        bool
+	paused         bool
+	start          time.Time // moved forward by the time spent paused
+	began          time.Time // when the session was started
+	elapsed        time.Duration
+	phaseDurations []time.Duration // phase lengths of the session, including skips and extensions
+	phaseTimes     []time.Duration // time spent in each phase the session got to, until the last phase change
+	phaseEntered   time.Duration   // elapsed time when the current phase was entered
+	phase          timerPhase
+	log            *config.SessionLog // noted down when the session started
+	restored       bool               // picked up again from a saved session rather than started
+}
+
+// idle reports whether there is no session to show, either because none was
+// started or because the last one completed
+func (e timerEngine) idle() bool {
+	return (!e.running && e.phase == phaseNotStarted) || e.phase == phaseCompleted
+}
+
+// temp returns the temperature of the current phase
+func (e timerEngine) temp() int {
+	return phaseTemp(e.profile.Phases, e.phase)
+}
+
+// begin starts a new session of the given profile and reports whether the
+// phase changed, so the first phase can be rung in
+func (e *timerEngine) begin(profile config.Profile, now time.Time) bool {
+	*e = timerEngine{
+		profile:        profile,
+		running:        true,
+		start:          now,
+		began:          now,
+		phaseDurations: phaseDurations(profile.Phases),
+	}
+	return e.updatePhase()
+}
+
+// sessionStarted reports whether a new session was started between before
+// and after. Picking a saved session up again doesn't count.
+func sessionStarted(before, after timerEngine) bool {
+	return !before.running && after.running && !after.restored
+}
+
+// stop ends the session without completing it
+func (e *timerEngine) stop() {
+	*e = timerEngine{}
+}
+
+// tick moves the session forward to now and reports whether the phase changed
+func (e *timerEngine) tick(now time.Time) bool {
+	if !e.running || e.paused {
+		return false
+	}
+	e.elapsed = now.Sub(e.start)
+	return e.updatePhase()
+}
+
+// updatePhase works out the current phase from the elapsed time and the
+// session's phase durations, and reports whether it changed
+func (e *timerEngine) updatePhase() bool {
+	oldPhase := e.phase
+	e.phase = phaseAt(e.phaseDurations, e.elapsed)
+	if e.phase != oldPhase {
+		e.countPhaseTimes(oldPhase)
+	}
+	if e.phase == phaseCompleted {
+		e.running = false
+		e.paused = false
+	}
+	return oldPhase != e.phase && e.phase != phaseNotStarted
+}
+
+// countPhaseTimes adds the time spent since the last phase change to the
+// phases the session just moved on from. Going forward, possibly past several
+// phases at once, each phase gets the time up to its end. Going back, it all
+// goes to the phase that was left.
+func (e *timerEngine) countPhaseTimes(oldPhase timerPhase) {
+	if oldPhase == phaseCompleted {
+		return
+	}
+	newPhase := e.phase
+	if newPhase == phaseCompleted {
+		newPhase = timerPhase(len(e.phaseDurations) + 1)
+	}
+	times := append([]time.Duration(nil), e.phaseTimes...)
+	add := func(phase timerPhase, d time.Duration) {
+		if int(phase) > len(e.phaseDurations) {
+			return
+		}
+		for len(times) < int(phase) {
+			times = append(times, 0)
+		}
+		if phase >= 1 {
+			times[phase-1] += max(d, 0)
+		}
+	}
+
+	if oldPhase >= 1 && newPhase < oldPhase {
+		add(oldPhase, e.elapsed-e.phaseEntered)
+		e.phaseEntered = e.elapsed
+	}
+	for p := max(oldPhase, 1); p < newPhase; p++ {
+		end := max(min(phaseStart(e.phaseDurations, p+1), e.elapsed), e.phaseEntered)
+		add(p, end-e.phaseEntered)
+		e.phaseEntered = end
+	}
+	add(min(newPhase, timerPhase(len(e.phaseDurations))), 0)
+	e.phaseTimes = times
+}
+
+// currentPhaseTimes returns the time spent in each phase the session got to,
+// including the current phase up to now
+func (e timerEngine) currentPhaseTimes() []time.Duration {
+	times := append([]time.Duration(nil), e.phaseTimes...)
+	if e.phase >= 1 && int(e.phase) <= len(times) {
+		times[e.phase-1] += max(e.elapsed-e.phaseEntered, 0)
+	}
+	return times
+}
+
+// togglePause pauses or resumes the session. Resuming moves the start time
+// forward by the time spent paused so the phase boundaries stay where they
+// were relative to the elapsed time.
+func (e *timerEngine) togglePause(now time.Time) {
+	if !e.running {
+		return
+	}
+	if e.paused {
+		e.start = now.Add(-e.elapsed)
+		e.paused = false
+	} else {
+		e.elapsed = now.Sub(e.start)
+		e.paused = true
+	}
+}
+
+// switchProfile carries the session on with another profile's phases and
+// reports whether that changed the phase
+func (e *timerEngine) switchProfile(profile config.Profile) bool {
+	if !e.running {
+		return false
+	}
+	// The time so far stays with the phase it was spent in
+	e.phaseTimes = e.currentPhaseTimes()
+	e.phaseTimes = e.phaseTimes[:min(len(e.phaseTimes), len(profile.Phases))]
+	e.phaseEntered = e.elapsed
+	e.profile = profile
+	e.phaseDurations = phaseDurations(profile.Phases)
+	return e.updatePhase()
+}
+
+// skip moves the session to the next phase (step 1) or back to the start of
+// the previous one (step -1), and reports whether the phase changed. Moving
+// back from the first phase restarts it. Only the session's phase durations
+// change, so the rest of the timeline follows along.
+func (e *timerEngine) skip(step int, now time.Time) bool {
+	if !e.running || e.phase < 1 {
+		return false
+	}
+	if !e.paused {
+		e.elapsed = now.Sub(e.start)
+	}
+
+	durations := append([]time.Duration(nil), e.phaseDurations...)
+	current := int(e.phase) - 1
+	if step > 0 {
+		// End the current phase now
+		durations[current] = e.elapsed - phaseStart(durations, e.phase)
+	} else {
+		// Stretch the previous phase so it runs again in full from now, and
+		// give the phase after it its full length back
+		target := max(current-1, 0)
+		configured := phaseDurations(e.profile.Phases)
+		durations[target] = e.elapsed - phaseStart(durations, timerPhase(target+1)) + configured[target]
+		for i := target + 1; i <= current; i++ {
+			durations[i] = configured[i]
+		}
+	}
+	e.phaseDurations = durations
+	return e.updatePhase()
+}
+
+// extend adds time to the current phase
+func (e *timerEngine) extend(extra time.Duration) {
+	if !e.running || e.phase < 1 {
+		return
+	}
+	durations := append([]time.Duration(nil), e.phaseDurations...)
+	durations[e.phase-1] += extra
+	e.phaseDurations = durations
+}
+
+// phaseDurations returns the configured length of each phase
+func phaseDurations(phases []config.Phase) []time.Duration {
+	durations := make([]time.Duration, len(phases))
+	for i, p := range phases {
+		durations[i] = time.Duration(p.DurationMinutes) * time.Minute
+	}
+	return durations
+}
+
+// phaseStart returns how far into the session a phase starts
+func phaseStart(durations []time.Duration, phase timerPhase) time.Duration {
+	start := time.Duration(0)
+	for i := 0; i < int(phase)-1 && i < len(durations); i++ {
+		start += durations[i]
+	}
+	return start
+}
+
+// totalDuration returns the combined length of all phases
+func totalDuration(durations []time.Duration) time.Duration {
+	return phaseStart(durations, timerPhase(len(durations)+1))
+}
+
+// phaseAt returns the phase a timer is in after running for elapsed
+func phaseAt(durations []time.Duration, elapsed time.Duration) timerPhase {
+	end := time.Duration(0)
+	for i, d := range durations {
+		end += d
+		if elapsed < end {
+			return timerPhase(i + 1)
+		}
+	}
+	return phaseCompleted
+}
+
+// phaseTemp returns the temperature of a phase, or 0 outside of a phase
+func phaseTemp(phases []config.Phase, phase timerPhase) int {
+	if phase < 1 || int(phase) > len(phases) {
+		return 0
+	}
+	return phases[phase-1].Temp
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+var testProfile = config.Profile{
+	Name: "Test",
+	Phases: []config.Phase{
+		{DurationMinutes: 10, Temp: 350},
+		{DurationMinutes: 10, Temp: 375},
+		{DurationMinutes: 10, Temp: 400},
+	},
+}
+
+func recordedPhaseTimes(record config.SessionRecord) []time.Duration {
+	var durations []time.Duration
+	for _, p := range record.Phases {
+		durations = append(durations, p.Duration)
+	}
+	return durations
+}
+
+func TestHistoryRecordNextAndBack(t *testing.T) {
+	start := time.Date(2026, 1, 2, 20, 0, 0, 0, time.UTC)
+	var e timerEngine
+	e.begin(testProfile, start)
+
+	e.tick(start.Add(5 * time.Minute))
+	e.skip(1, start.Add(5*time.Minute))
+	e.tick(start.Add(6 * time.Minute))
+	e.skip(-1, start.Add(6*time.Minute))
+	if e.phase != 1 {
+		t.Fatalf("phase after going back = %d, want 1", e.phase)
+	}
+	for e.running {
+		e.skip(1, start.Add(6*time.Minute))
+	}
+
+	got := recordedPhaseTimes(e.historyRecord(start.Add(6 * time.Minute)))
+	want := []time.Duration{5 * time.Minute, time.Minute, 0}
+	if !slices.Equal(got, want) {
+		t.Errorf("phase times = %v, want %v", got, want)
+	}
+}
+
+func TestHistoryRecordExtendAndComplete(t *testing.T) {
+	start := time.Date(2026, 1, 2, 20, 0, 0, 0, time.UTC)
+	var e timerEngine
+	e.begin(testProfile, start)
+	e.extend(time.Minute)
+
+	// A single tick can pass several phase ends
+	e.tick(start.Add(12 * time.Minute))
+	e.tick(start.Add(45 * time.Minute))
+	if e.phase != phaseCompleted {
+		t.Fatalf("phase = %d, want completed", e.phase)
+	}
+
+	got := recordedPhaseTimes(e.historyRecord(start.Add(31 * time.Minute)))
+	want := []time.Duration{11 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	if !slices.Equal(got, want) {
+		t.Errorf("phase times = %v, want %v", got, want)
+	}
+}
+
+func TestHistoryRecordStoppedAfterResume(t *testing.T) {
+	start := time.Date(2026, 1, 2, 20, 0, 0, 0, time.UTC)
+	var e timerEngine
+	e.begin(testProfile, start)
+	e.tick(start.Add(3 * time.Minute))
+	e.skip(1, start.Add(3*time.Minute))
+	e.tick(start.Add(4 * time.Minute))
+	e.skip(-1, start.Add(4*time.Minute))
+
+	// Saved and picked up again a minute later
+	state := config.SessionState{
+		Profile:        e.profile.Name,
+		Start:          e.start,
+		Began:          e.began,
+		PhaseDurations: e.phaseDurations,
+		PhaseTimes:     e.phaseTimes,
+		PhaseEntered:   e.phaseEntered,
+	}
+	var resumed timerEngine
+	resumed.resume(state, testProfile, start.Add(5*time.Minute))
+	if resumed.phase != 1 {
+		t.Fatalf("resumed phase = %d, want 1", resumed.phase)
+	}
+
+	before := resumed
+	resumed.stop()
+	record, ok := endedSession(before, resumed, start.Add(7*time.Minute))
+	if !ok {
+		t.Fatal("stopping the session didn't end it")
+	}
+	got := recordedPhaseTimes(record)
+	want := []time.Duration{6 * time.Minute, time.Minute}
+	if !slices.Equal(got, want) {
+		t.Errorf("phase times = %v, want %v", got, want)
+	}
+}
+
+func TestResumeReturnsSessionThatRanOut(t *testing.T) {
+	start := time.Date(2026, 1, 2, 20, 0, 0, 0, time.UTC)
+	state := config.SessionState{
+		Profile:        testProfile.Name,
+		Start:          start,
+		Began:          start,
+		PhaseDurations: phaseDurations(testProfile.Phases),
+		PhaseTimes:     []time.Duration{0},
+	}
+	var e timerEngine
+	changed, finished := e.resume(state, testProfile, start.Add(time.Hour))
+	if !changed || e.running {
+		t.Errorf("resume changed = %v, running = %v, want a completed session", changed, e.running)
+	}
+	if finished == nil {
+		t.Fatal("resume didn't return the finished session")
+	}
+	if !finished.Completed || !finished.End.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("finished session = %+v, want completed at the end of its phases", finished)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// runHistory runs the history subcommands. export is the only one for now.
+func runHistory(args []string) error {
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprintf(os.Stderr, "Usage: cclock history export [--format csv|json|ics] [--since DATE]\n")
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("history export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "output format: csv, json or ics")
+	since := flags.String("since", "", "only export sessions started on or after this date, e.g. 2025-01-31")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cclock history export [--format csv|json|ics] [--since DATE]\n\nPrint the session history.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	var sinceTime time.Time
+	if *since != "" {
+		var err error
+		sinceTime, err = time.ParseInLocation(time.DateOnly, *since, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid date %q, use e.g. 2025-01-31", *since)
+		}
+	}
+
+	records, err := config.LoadHistory()
+	if err != nil {
+		return fmt.Errorf("Error loading history: %w", err)
+	}
+	var selected []config.SessionRecord
+	for _, record := range records {
+		if !record.Start.Before(sinceTime) {
+			selected = append(selected, record)
+		}
+	}
+
+	switch *format {
+	case "csv":
+		return exportCSV(os.Stdout, selected)
+	case "json":
+		return exportJSON(os.Stdout, selected)
+	case "ics":
+		return exportICS(os.Stdout, selected, time.Now())
+	default:
+		return fmt.Errorf("unknown format %q, use csv, json or ics", *format)
+	}
+}
+
+// exportCSV writes a row per session. The phases' temperatures and lengths
+// in seconds are separated by semicolons.
+func exportCSV(w io.Writer, records []config.SessionRecord) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"profile", "start", "end", "duration_seconds", "completed", "phases", "temps", "phase_seconds", "grams", "strain", "rating", "notes"})
+	for _, r := range records {
+		temps := make([]string, len(r.Phases))
+		lengths := make([]string, len(r.Phases))
+		for i, p := range r.Phases {
+			temps[i] = strconv.Itoa(p.Temp)
+			lengths[i] = strconv.Itoa(int(p.Duration.Seconds()))
+		}
+		var log config.SessionLog
+		if r.Log != nil {
+			log = *r.Log
+		}
+		grams, rating := "", ""
+		if log.Grams > 0 {
+			grams = formatGrams(log.Grams)
+		}
+		if log.Rating > 0 {
+			rating = strconv.Itoa(log.Rating)
+		}
+		out.Write([]string{
+			r.Profile,
+			r.Start.Format(time.RFC3339),
+			r.End.Format(time.RFC3339),
+			strconv.Itoa(int(r.Duration().Seconds())),
+			strconv.FormatBool(r.Completed),
+			strconv.Itoa(len(r.Phases)),
+			strings.Join(temps, ";"),
+			strings.Join(lengths, ";"),
+			grams,
+			log.Strain,
+			rating,
+			log.Notes,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// exportedSession is a session in the JSON export, with lengths in seconds
+// rather than the history's nanoseconds
+type exportedSession struct {
+	Profile         string             `json:"profile"`
+	Start           time.Time          `json:"start"`
+	End             time.Time          `json:"end"`
+	DurationSeconds int                `json:"duration_seconds"`
+	Completed       bool               `json:"completed"`
+	Phases          []exportedPhase    `json:"phases"`
+	Log             *config.SessionLog `json:"log,omitempty"`
+}
+
+type exportedPhase struct {
+	Name            string `json:"name"`
+	Temp            int    `json:"temp"`
+	DurationSeconds int    `json:"duration_seconds"`
+}
+
+// exportJSON writes the sessions as a JSON array
+func exportJSON(w io.Writer, records []config.SessionRecord) error {
+	sessions := make([]exportedSession, len(records))
+	for i, r := range records {
+		sessions[i] = exportedSession{
+			Profile:         r.Profile,
+			Start:           r.Start,
+			End:             r.End,
+			DurationSeconds: int(r.Duration().Seconds()),
+			Completed:       r.Completed,
+			Phases:          make([]exportedPhase, len(r.Phases)),
+			Log:             r.Log,
+		}
+		for j, p := range r.Phases {
+			sessions[i].Phases[j] = exportedPhase{Name: recordPhaseName(p, j), Temp: p.Temp, DurationSeconds: int(p.Duration.Seconds())}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sessions)
+}
+
+// exportICS writes an iCalendar file with an event per session
+func exportICS(w io.Writer, records []config.SessionRecord, now time.Time) error {
+	const stamp = "20060102T150405Z"
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//ChillClock//cclock//EN",
+		"CALSCALE:GREGORIAN",
+	}
+	for _, r := range records {
+		summary := r.Profile
+		if !r.Completed {
+			summary += " (stopped)"
+		}
+		description := []string{"Profile: " + r.Profile}
+		for i, p := range r.Phases {
+			description = append(description, fmt.Sprintf("%s: %d° for %s", recordPhaseName(p, i), p.Temp, formatDuration(p.Duration)))
+		}
+		if r.Log != nil {
+			description = append(description, describeLog(*r.Log)...)
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%d@chillclock", r.Start.UnixNano()),
+			"DTSTAMP:"+now.UTC().Format(stamp),
+			"DTSTART:"+r.Start.UTC().Format(stamp),
+			"DTEND:"+r.End.UTC().Format(stamp),
+			"SUMMARY:"+icsEscape(summary),
+			"DESCRIPTION:"+icsEscape(strings.Join(description, "\n")),
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, icsFold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordPhaseName returns the name of the i-th phase of a session, or
+// "Phase N" when it has none
+func recordPhaseName(p config.PhaseRecord, i int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("Phase %d", i+1)
+}
+
+// icsEscape escapes text for an iCalendar property value
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsFold breaks a content line into lines of at most 75 bytes, as iCalendar
+// requires, without splitting a UTF-8 character
+func icsFold(line string) string {
+	var folded strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			folded.WriteString("\r\n ")
+			width = 1
+		}
+		folded.WriteRune(r)
+		width += size
+	}
+	return folded.String()
+}
+
+// describeLog returns a line for each thing noted down about a session
+func describeLog(log config.SessionLog) []string {
+	var lines []string
+	if log.Grams > 0 {
+		lines = append(lines, "Loaded: "+formatGrams(log.Grams)+"g")
+	}
+	if log.Strain != "" {
+		lines = append(lines, "Strain: "+log.Strain)
+	}
+	if log.Rating > 0 {
+		lines = append(lines, fmt.Sprintf("Rating: %d/5", log.Rating))
+	}
+	if log.Notes != "" {
+		lines = append(lines, "Notes: "+log.Notes)
+	}
+	return lines
+}
+
+// formatGrams formats an amount in grams without trailing zeros, e.g. 0.15
+func formatGrams(grams float64) string {
+	return strconv.FormatFloat(grams, 'f', -1, 64)
+}
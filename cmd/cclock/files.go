@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// statusFiles are the files shared with status bars: the sinks cclock writes
+// and the directories it watches for click files
+type statusFiles struct {
+	sinks       []*statusSink
+	triggerDirs []string
+}
+
+// setupStatusFiles works out where the status bar files go from the config
+// and the flags, and creates their directories. Sinks on stdout are only
+// written when withStdout is set, as the clock needs stdout for itself.
+//
+// The files used to live in the home directory. When ~/dhv_timer.txt is still
+// there it's turned into a link to the new state file and click files in the
+// home directory keep working, so older status bar setups don't break.
+// Removing ~/dhv_timer.txt stops both.
+func setupStatusFiles(cfg config.Config, opts *runOptions, withStdout bool) (statusFiles, error) {
+	if opts.stateFile != "" {
+		cfg.StateFile = opts.stateFile
+	}
+	if opts.triggerDir != "" {
+		cfg.TriggerDir = opts.triggerDir
+	}
+	var files statusFiles
+	triggerDir, err := cfg.TriggerDirPath()
+	if err != nil {
+		return files, err
+	}
+	files.triggerDirs = []string{triggerDir}
+
+	stateFile, err := cfg.StateFilePath()
+	if err != nil {
+		return files, err
+	}
+	writesStateFile := false
+	for _, sink := range cfg.StatusSinks() {
+		format, ok := statusFormats[sink.Format]
+		if !ok {
+			return files, fmt.Errorf("Unknown status bar format %q, use waybar, i3bar, i3blocks or polybar", sink.Format)
+		}
+		path, err := cfg.SinkPath(sink)
+		if err != nil {
+			return files, err
+		}
+		if path == "-" {
+			if !withStdout {
+				fmt.Fprintf(os.Stderr, "Skipping the %s sink on stdout, only cclock daemon can write there\n", sink.Format)
+				continue
+			}
+		} else if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return files, fmt.Errorf("Error creating status bar file directory: %w", err)
+		}
+		writesStateFile = writesStateFile || samePath(path, stateFile)
+		files.sinks = append(files.sinks, &statusSink{format: format, path: path})
+	}
+
+	if err := os.MkdirAll(files.triggerDirs[0], 0700); err != nil {
+		return files, fmt.Errorf("Error creating trigger directory: %w", err)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return files, nil
+	}
+	if !writesStateFile {
+		// Nothing would keep a link to the state file up to date
+		return files, nil
+	}
+	if linkLegacyStateFile(filepath.Join(homeDir, config.StateFileName), stateFile) && !samePath(homeDir, files.triggerDirs[0]) {
+		files.triggerDirs = append(files.triggerDirs, homeDir)
+	}
+	return files, nil
+}
+
+// linkLegacyStateFile points the status bar file in the home directory at the
+// state file, and reports whether there was one to keep up to date
+func linkLegacyStateFile(legacy, stateFile string) bool {
+	info, err := os.Lstat(legacy)
+	if err != nil {
+		return false
+	}
+	if samePath(legacy, stateFile) {
+		// The state file was configured to stay where it was
+		return false
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(legacy); err == nil && target == stateFile {
+			return true
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "The status bar file moved to %s and %s now links to it. Point your status bar at the new path and delete the link.\n", stateFile, legacy)
+	}
+
+	if err := os.Remove(legacy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error replacing %s: %v\n", legacy, err)
+		return true
+	}
+	if err := os.Symlink(stateFile, legacy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error linking %s: %v\n", legacy, err)
+	}
+	return true
+}
+
+// watchTriggers watches every trigger directory until done is closed
+func (f statusFiles) watchTriggers(send func(tea.Msg), done <-chan struct{}) {
+	for _, dir := range f.triggerDirs {
+		go watchTriggers(dir, send, done)
+	}
+}
+
+// samePath reports whether two paths name the same location
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
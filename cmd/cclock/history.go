@@ -0,0 +1,175 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// recordHistory adds the session to the history if it ended between before
+// and after
+func recordHistory(before, after timerEngine, now time.Time) error {
+	if record, ok := endedSession(before, after, now); ok {
+		return config.AppendHistory(record)
+	}
+	return nil
+}
+
+// endedSession returns the history record of the session if it ended between
+// before and after
+func endedSession(before, after timerEngine, now time.Time) (config.SessionRecord, bool) {
+	if !before.running || after.running {
+		return config.SessionRecord{}, false
+	}
+	if after.phase == phaseCompleted {
+		return after.historyRecord(after.start.Add(totalDuration(after.phaseDurations))), true
+	}
+	// Stopped early. The engine is cleared by then, so the session is taken
+	// from before, brought up to now.
+	if !before.paused {
+		before.elapsed = now.Sub(before.start)
+	}
+	return before.historyRecord(now), true
+}
+
+// historyRecord describes the session for the history. Only the phases it
+// got to are included, each with the time actually spent in it, so going back
+// a phase doesn't move time from one phase to another.
+func (e timerEngine) historyRecord(end time.Time) config.SessionRecord {
+	record := config.SessionRecord{
+		Profile:   e.profile.Name,
+		Start:     e.began,
+		End:       end,
+		Completed: e.phase == phaseCompleted,
+		Log:       e.log,
+	}
+	for i, d := range e.currentPhaseTimes() {
+		phase := config.PhaseRecord{Duration: d}
+		if i < len(e.profile.Phases) {
+			phase.Name = e.profile.Phases[i].Name
+			phase.Temp = e.profile.Phases[i].Temp
+		}
+		record.Phases = append(record.Phases, phase)
+	}
+	return record
+}
+
+// periodStats sums up the sessions started in a day or a week
+type periodStats struct {
+	start     time.Time
+	sessions  int
+	completed int
+	total     time.Duration
+}
+
+// average returns the average length of the period's sessions
+func (p periodStats) average() time.Duration {
+	if p.sessions == 0 {
+		return 0
+	}
+	return p.total / time.Duration(p.sessions)
+}
+
+func (p *periodStats) add(record config.SessionRecord) {
+	p.sessions++
+	p.total += record.Duration()
+	if record.Completed {
+		p.completed++
+	}
+}
+
+// dayStart returns midnight at the start of t's day
+func dayStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// weekStart returns midnight at the start of t's week, which starts on Monday
+func weekStart(t time.Time) time.Time {
+	day := dayStart(t)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// historyPeriods sums up the history for the last count days, or weeks when
+// days is 7, oldest first
+func historyPeriods(records []config.SessionRecord, now time.Time, count, days int) []periodStats {
+	first := dayStart(now)
+	if days == 7 {
+		first = weekStart(now)
+	}
+	first = first.AddDate(0, 0, -days*(count-1))
+
+	periods := make([]periodStats, count)
+	for i := range periods {
+		periods[i].start = first.AddDate(0, 0, days*i)
+	}
+	end := first.AddDate(0, 0, days*count)
+	for _, record := range records {
+		if record.Start.Before(first) || !record.Start.Before(end) {
+			continue
+		}
+		for i := len(periods) - 1; i >= 0; i-- {
+			if !record.Start.Before(periods[i].start) {
+				periods[i].add(record)
+				break
+			}
+		}
+	}
+	return periods
+}
+
+// doseStats sums up the logged sessions of a profile with the same amount
+// loaded
+type doseStats struct {
+	profile     string
+	grams       float64
+	sessions    int
+	rated       int
+	ratingTotal int
+}
+
+// averageRating returns the average rating of the rated sessions, or 0 when
+// none were rated
+func (d doseStats) averageRating() float64 {
+	if d.rated == 0 {
+		return 0
+	}
+	return float64(d.ratingTotal) / float64(d.rated)
+}
+
+// historyDoses groups the sessions logged with an amount by profile and
+// amount, best rated first
+func historyDoses(records []config.SessionRecord) []doseStats {
+	type doseKey struct {
+		profile string
+		grams   float64
+	}
+	index := map[doseKey]int{}
+	var doses []doseStats
+	for _, record := range records {
+		if record.Log == nil || record.Log.Grams <= 0 {
+			continue
+		}
+		key := doseKey{record.Profile, record.Log.Grams}
+		i, ok := index[key]
+		if !ok {
+			i = len(doses)
+			index[key] = i
+			doses = append(doses, doseStats{profile: key.profile, grams: key.grams})
+		}
+		doses[i].sessions++
+		if record.Log.Rating > 0 {
+			doses[i].rated++
+			doses[i].ratingTotal += record.Log.Rating
+		}
+	}
+	slices.SortStableFunc(doses, func(a, b doseStats) int {
+		if c := cmp.Compare(b.averageRating(), a.averageRating()); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.sessions, a.sessions)
+	})
+	return doses
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// pendingStart is a session waiting for the user to confirm starting it
+// despite a warning
+type pendingStart struct {
+	profile int
+	warning string
+}
+
+// sessionLimit returns when the usage limits next allow a session to start
+// and why, or a zero time when one may start at now. It goes by the session
+// history, so a history that can't be read doesn't stand in the way.
+func sessionLimit(limits config.Limits, now time.Time) (time.Time, string) {
+	if limits.MaxPerDay <= 0 && limits.MinGapMinutes <= 0 {
+		return time.Time{}, ""
+	}
+	history, err := config.LoadHistory()
+	if err != nil {
+		return time.Time{}, ""
+	}
+
+	var until time.Time
+	var reason string
+	if limits.MaxPerDay > 0 {
+		today := dayStart(now)
+		count := 0
+		for _, record := range history {
+			if !record.Start.Before(today) {
+				count++
+			}
+		}
+		if count >= limits.MaxPerDay {
+			until = today.AddDate(0, 0, 1)
+			reason = fmt.Sprintf("Daily limit of %d sessions reached", limits.MaxPerDay)
+		}
+	}
+	if limits.MinGapMinutes > 0 {
+		var lastEnd time.Time
+		for _, record := range history {
+			if record.End.After(lastEnd) {
+				lastEnd = record.End
+			}
+		}
+		next := lastEnd.Add(time.Duration(limits.MinGapMinutes) * time.Minute)
+		if next.After(now) && next.After(until) {
+			until = next
+			reason = fmt.Sprintf("Less than %d minutes since the last session", limits.MinGapMinutes)
+		}
+	}
+	return until, reason
+}
+
+// limitMessage describes a limit and when the next session is available
+func limitMessage(until time.Time, reason string, now time.Time) string {
+	at := until.Format("15:04")
+	if !dayStart(until).Equal(dayStart(now)) {
+		at = until.Format("Mon 15:04")
+	}
+	return fmt.Sprintf("%s, next session available at %s", reason, at)
+}
+
+// refusal returns why the limits refuse to start a session at now, or ""
+// when they don't. Limits that only warn let it start, as there's nobody to
+// ask.
+func refusal(limits config.Limits, now time.Time) string {
+	if limits.Enforce != "refuse" {
+		return ""
+	}
+	if until, reason := sessionLimit(limits, now); !until.IsZero() {
+		return limitMessage(until, reason, now)
+	}
+	return ""
+}
+
+// refreshLimit works out again when the next session is available, for the
+// clock to show
+func (m model) refreshLimit() model {
+	m.limitUntil, m.limitReason = sessionLimit(m.config.Limits, time.Now())
+	return m
+}
+
+// handleStartConfirmInput answers the prompt asking whether to start a
+// session despite a warning
+func (m model) handleStartConfirmInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "y":
+		profile := m.pendingStart.profile
+		m.pendingStart = nil
+		return m.startTimer(profile)
+	case "n", "esc", "q":
+		m.pendingStart = nil
+	}
+	return m, nil
+}
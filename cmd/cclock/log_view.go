@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/unquenchedservant/ChillClock/config"
+	util "github.com/unquenchedservant/ChillClock/utilities"
+)
+
+// Rows of the log form
+const (
+	logGrams = iota
+	logStrain
+	logRating
+	logNotes
+	logFieldCount
+)
+
+var logFieldNames = [logFieldCount]string{"Grams", "Strain", "Rating (1-5)", "Notes"}
+
+// logForm asks for the amount, strain and rating of a session
+type logForm struct {
+	values   [logFieldCount]string
+	selected int
+	err      string
+	// finished session waiting for its log before it's added to the history,
+	// nil while logging the running session
+	record *config.SessionRecord
+	began  time.Time // start of the running session being logged
+}
+
+// openLog shows the log form for the running session, or for record when
+// it's a finished one. A finished session still waiting for its log is added
+// to the history without one.
+func (m model) openLog(record *config.SessionRecord) model {
+	m.flushLog()
+	m.logForm = &logForm{record: record, began: m.timer.began}
+	m.mode = viewLog
+	return m
+}
+
+// sessionEnded adds a finished session to the history, asking for its log
+// first when it should
+func (m model) sessionEnded(record config.SessionRecord) model {
+	switch {
+	case m.logForm != nil && m.logForm.record == nil && m.logForm.began.Equal(record.Start):
+		// The session ended while its log was being filled in
+		form := *m.logForm
+		form.record = &record
+		m.logForm = &form
+	case m.config.LogPrompt == "finish" && record.Log == nil:
+		m = m.openLog(&record)
+	default:
+		config.AppendHistory(record)
+	}
+	return m.refreshLimit()
+}
+
+// flushLog adds the finished session waiting for its log to the history as
+// it is, so it isn't lost
+func (m model) flushLog() {
+	if m.logForm != nil && m.logForm.record != nil {
+		config.AppendHistory(*m.logForm.record)
+	}
+}
+
+// closeLog puts the log with its session and goes back to the clock. A
+// skipped form (nil log) leaves a log the session already has alone.
+func (m model) closeLog(log *config.SessionLog) model {
+	if form := m.logForm; form.record != nil {
+		if log != nil {
+			form.record.Log = log
+		}
+		config.AppendHistory(*form.record)
+		m = m.refreshLimit()
+	} else if log != nil && m.timer.running && m.timer.began.Equal(form.began) {
+		m.timer.log = log
+		m.timer.save()
+	}
+	m.logForm = nil
+	m.mode = viewClock
+	return m
+}
+
+func (m model) handleLogInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	form := *m.logForm
+	m.logForm = &form
+	value := &form.values[form.selected]
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		return m.closeLog(nil), nil
+	case "up", "shift+tab":
+		form.selected = max(form.selected-1, 0)
+	case "down", "tab":
+		form.selected = min(form.selected+1, logFieldCount-1)
+	case "enter":
+		if form.selected < logFieldCount-1 {
+			form.selected++
+			return m, nil
+		}
+		log, err := form.log()
+		if err != nil {
+			form.err = err.Error()
+			return m, nil
+		}
+		return m.closeLog(log), nil
+	case "backspace":
+		if len(*value) > 0 {
+			runes := []rune(*value)
+			*value = string(runes[:len(runes)-1])
+		}
+	default:
+		if msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace {
+			break
+		}
+		input := string(msg.Runes)
+		if msg.Type == tea.KeySpace {
+			input = " "
+		}
+		switch form.selected {
+		case logGrams:
+			if strings.Trim(input, "0123456789.") != "" {
+				return m, nil
+			}
+		case logRating:
+			if len(input) != 1 || input < "1" || input > "5" {
+				return m, nil
+			}
+			*value = ""
+		}
+		*value += input
+	}
+	return m, nil
+}
+
+// log returns what was filled in, or nil when nothing was
+func (f logForm) log() (*config.SessionLog, error) {
+	var log config.SessionLog
+	if grams := f.values[logGrams]; grams != "" {
+		amount, err := strconv.ParseFloat(grams, 64)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("%q isn't an amount in grams, e.g. 0.15", grams)
+		}
+		log.Grams = amount
+	}
+	if rating := f.values[logRating]; rating != "" {
+		log.Rating, _ = strconv.Atoi(rating)
+		if log.Rating < 1 || log.Rating > 5 {
+			return nil, errors.New("the rating goes from 1 to 5")
+		}
+	}
+	log.Strain = strings.TrimSpace(f.values[logStrain])
+	log.Notes = strings.TrimSpace(f.values[logNotes])
+	if log == (config.SessionLog{}) {
+		return nil, nil
+	}
+	return &log, nil
+}
+
+func (m model) renderLogView() string {
+	var output strings.Builder
+	form := m.logForm
+
+	profile := m.timer.profile.Name
+	title := "Log This Session"
+	if form.record != nil {
+		profile = form.record.Profile
+		title = "Log The Session Just Finished"
+	}
+	output.WriteString("\n")
+	output.WriteString(util.CenterText(util.GetYellowStyle().Bold(true).Render(title+" - "+profile), m.width))
+	output.WriteString("\n\n")
+
+	for i, name := range logFieldNames {
+		if i == form.selected {
+			line := fmt.Sprintf("  ▶ %s: %s_", name, form.values[i])
+			output.WriteString(util.CenterText(util.GetEditingStyle().Render(line), m.width))
+		} else {
+			line := fmt.Sprintf("    %s: %s", name, form.values[i])
+			output.WriteString(util.CenterText(util.GetNormalStyle().Render(line), m.width))
+		}
+		output.WriteString("\n")
+	}
+
+	output.WriteString("\n")
+	if form.err != "" {
+		output.WriteString(util.CenterText(util.GetRedStyle().Render(form.err), m.width))
+		output.WriteString("\n")
+	}
+	helpText := "↑/↓: Navigate | Enter: Next | Esc: Skip"
+	if form.selected == logFieldCount-1 {
+		helpText = "↑/↓: Navigate | Enter: Save | Esc: Skip"
+	}
+	output.WriteString(util.CenterText(util.GetNormalStyle().Render(helpText), m.width))
+
+	return output.String()
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"time"
 	"runtime/debug"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/unquenchedservant/ChillClock/config"
+	"github.com/unquenchedservant/ChillClock/control"
+	util "github.com/unquenchedservant/ChillClock/utilities"
 )
+
 var version = getVersion()
 
 func getVersion() string {
@@ -21,65 +27,181 @@ func getVersion() string {
 }
 
 type model struct {
-	width         int
-	height        int
-	config        config.Config
-	timerRunning  bool
-	timerStart    time.Time
-	timerElapsed  time.Duration
-	currentPhase  timerPhase
-	timer         int
-	timerDefault  int
-	configPage    int
-	lastPhase     timerPhase // Track last phase for ding detection
-	mode          viewMode
-	selectedField configField
-	editingField  bool
-	inputBuffer   string
-	previousValue int // Store previous value to restore if input is blank
+	width          int
+	height         int
+	config         config.Config
+	timer          timerEngine
+	timerDefault   int // index of the profile started by Enter/Space
+	configPage     int // index of the profile shown in the config view
+	mode           viewMode
+	selectedField  configField
+	editingField   bool
+	inputBuffer    string
+	previousValue  int                  // Store previous value to restore if input is blank
+	pendingSession *config.SessionState // saved session waiting to be resumed or discarded
+	server         *control.Server      // control socket, publishes timer events to subscribers
+	files          statusFiles
+	texts          textTemplates
+	windowTitle    string             // terminal title last set
+	notifier       util.NotifierChain // notification backends in the order they're tried
+	notifyError    string             // why the last notification failed, shown under the timer
+	flash          util.Notification  // notification the screen flashes with
+	flashSequence  string             // terminal notification sent along with the flash
+	flashUntil     time.Time
+	history        []config.SessionRecord // sessions shown in the stats view
+	historyError   string                 // why the history couldn't be loaded
+	pendingStart   *pendingStart          // session waiting for a warning to be confirmed
+	limitUntil     time.Time              // when the usage limits allow the next session
+	limitReason    string
+	onBreak        *config.Break // tolerance break that's going on
+	breakChecked   time.Time     // when onBreak was last loaded
+	streak         int           // longest stretch of days without a session
+	logForm        *logForm      // dose log being filled in
 }
 
-const (
-	TIMER_1 = 1
-	TIMER_2 = 2
-	CFG_PAGE_1 = 0
-	CFG_PAGE_2 = 1
-	TIMER_DEFAULT = 1
-)
+// runOptions are the flags shared by the clock and the daemon
+type runOptions struct {
+	httpAddr   string
+	stateFile  string
+	triggerDir string
+}
+
+// addRunFlags defines the flags shared by the clock and the daemon
+func addRunFlags(flags *flag.FlagSet) *runOptions {
+	opts := &runOptions{}
+	flags.StringVar(&opts.httpAddr, "http", "", "also serve the control API over HTTP on this address, e.g. 127.0.0.1:8765")
+	flags.StringVar(&opts.stateFile, "state-file", "", "write the status bar file here instead of the configured path")
+	flags.StringVar(&opts.triggerDir, "trigger-dir", "", "watch for click files in this directory instead of the configured one")
+	return opts
+}
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(tickCmd(), watchForFileClick(), tea.EnterAltScreen)
+	return tea.Batch(tickCmd(), tea.EnterAltScreen)
 }
 
 func main() {
-	if err := config.EnsureConfigExists(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating config: %v\n", err)
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
+	flags := flag.NewFlagSet("cclock", flag.ExitOnError)
+	opts := addRunFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		fmt.Fprint(flags.Output(), "\nOptions for the clock and daemon:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
-	cfg, err := config.LoadConfig()
+	files, err := setupStatusFiles(cfg, opts, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	texts, err := parseTemplates(cfg.Templates)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	session, err := config.LoadSession()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading saved session: %v\n", err)
+	}
+
+	var p *tea.Program
+	handler := forwardRequests(func(call controlCall) { p.Send(call) })
+	desktop := util.NewDBusNotifier(notificationActionHandler(handler))
+	defer desktop.Close()
+	// Terminal notifications go out with the view instead, see dingCmd
+	notifier, err := buildNotifier(cfg, desktop, io.Discard)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
+	server, err := control.Listen(handler)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening control socket: %v\n", err)
+		os.Exit(1)
+	}
+	defer server.Close()
+
+	if opts.httpAddr != "" {
+		httpServer, err := serveHTTP(opts.httpAddr, cfg.HTTPOrigins, server)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			server.Close()
+			os.Exit(1)
+		}
+		defer httpServer.Close()
+	}
+
 	// Create initial model with config
 	initialModel := model{
-		config:        cfg,
-		currentPhase:  phaseNotStarted,
-		lastPhase:     phaseNotStarted,
-		mode:          viewClock,
-		selectedField: fieldPhase1DurationT1,
-		editingField:  false,
-		inputBuffer:   "",
-		previousValue: 0,
-		timerDefault: TIMER_1,
-		configPage: CFG_PAGE_1,
-	}
-
-	p := tea.NewProgram(initialModel, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+		config:         cfg,
+		mode:           viewClock,
+		selectedField:  0,
+		editingField:   false,
+		inputBuffer:    "",
+		previousValue:  0,
+		timerDefault:   max(cfg.ProfileIndex(cfg.DefaultProfile), 0),
+		configPage:     0,
+		pendingSession: session,
+		server:         server,
+		files:          files,
+		texts:          texts,
+		notifier:       notifier,
+	}
+	initialModel = initialModel.refreshLimit().refreshBreak()
+
+	p = tea.NewProgram(initialModel, tea.WithAltScreen())
+	go server.Serve()
+
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	files.watchTriggers(p.Send, stopWatching)
+
+	finalModel, err := p.Run()
+	if m, ok := finalModel.(model); ok {
+		m.flushLog()
+	}
+	// Don't leave the status bar showing a stale time. A running session
+	// stays saved so it can be resumed on the next launch.
+	files.writeTimerState(timerEngine{}, texts)
+	initialModel.resetTerminal()
+	if err != nil {
+		server.Close()
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// loadConfig creates the config file if needed and loads it
+func loadConfig() (config.Config, error) {
+	if err := config.EnsureConfigExists(); err != nil {
+		return config.Config{}, fmt.Errorf("Error creating config: %w", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.Config{}, fmt.Errorf("Error loading config: %w", err)
+	}
+	switch cfg.Limits.Enforce {
+	case "", "warn", "refuse":
+	default:
+		return config.Config{}, fmt.Errorf("Unknown limits enforce setting %q, use warn or refuse", cfg.Limits.Enforce)
+	}
+	switch cfg.LogPrompt {
+	case "", "start", "finish":
+	default:
+		return config.Config{}, fmt.Errorf("Unknown log_prompt %q, use start or finish", cfg.LogPrompt)
+	}
+	return cfg, nil
+}
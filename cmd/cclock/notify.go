@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/unquenchedservant/ChillClock/config"
+	"github.com/unquenchedservant/ChillClock/control"
+	util "github.com/unquenchedservant/ChillClock/utilities"
+)
+
+// notificationActions are the buttons on the notifications of a running timer
+var notificationActions = []util.NotificationAction{
+	{Key: "next", Label: "Next phase"},
+	{Key: "extend", Label: "+1 min"},
+	{Key: "stop", Label: "Stop"},
+}
+
+// notificationActionHandler returns a function that carries out the action
+// buttons pressed on notifications through handler, like requests from the
+// control socket
+func notificationActionHandler(handler control.Handler) func(key string) {
+	return func(key string) {
+		switch key {
+		case "next":
+			handler(control.Request{Command: "skip", Steps: 1})
+		case "extend":
+			handler(control.Request{Command: "extend", Seconds: 60})
+		case "stop":
+			handler(control.Request{Command: "stop"})
+		}
+	}
+}
+
+// buildNotifier builds the chain of notifiers configured in
+// notification_backends. dbus is the D-Bus notifier to use, shared so
+// notifications keep replacing each other when the chain is rebuilt.
+// terminal is where terminal notifications go, or nil when there's no
+// terminal and the terminal backends are left out.
+func buildNotifier(cfg config.Config, dbus *util.DBusNotifier, terminal io.Writer) (util.NotifierChain, error) {
+	var chain util.NotifierChain
+	for _, backend := range cfg.NotificationOrder() {
+		switch backend {
+		case "desktop":
+			chain = append(chain, util.DesktopNotifiers(dbus)...)
+		case "dbus":
+			chain = append(chain, dbus)
+		case "notify-send":
+			chain = append(chain, util.NotifySendNotifier{})
+		case "osascript":
+			chain = append(chain, util.OSAScriptNotifier{})
+		case "toast":
+			chain = append(chain, util.ToastNotifier{})
+		case "osc9", "osc777":
+			if terminal != nil {
+				osc := 9
+				if backend == "osc777" {
+					osc = 777
+				}
+				chain = append(chain, util.TerminalNotifier{W: terminal, OSC: osc})
+			}
+		case "webhook":
+			if cfg.NotificationWebhook == "" {
+				return nil, errors.New("The webhook notification backend needs notification_webhook set to a URL")
+			}
+			chain = append(chain, util.WebhookNotifier{URL: cfg.NotificationWebhook})
+		default:
+			return nil, fmt.Errorf("Unknown notification backend %q, use desktop, dbus, notify-send, osascript, toast, osc9, osc777 or webhook", backend)
+		}
+	}
+	return chain, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// save writes the running session to the state dir, or removes the saved
+// session once the timer is stopped or completed
+func (e timerEngine) save() error {
+	if !e.running {
+		return config.ClearSession()
+	}
+	return config.SaveSession(config.SessionState{
+		Profile:        e.profile.Name,
+		Start:          e.start,
+		Began:          e.began,
+		PhaseDurations: e.phaseDurations,
+		Paused:         e.paused,
+		PausedElapsed:  e.elapsed,
+		PhaseTimes:     e.phaseTimes,
+		PhaseEntered:   e.phaseEntered,
+		Log:            e.log,
+	})
+}
+
+// resume restores a saved session of the given profile and reports whether
+// the phase changed. The current phase is worked out again from the
+// wall-clock start time, so time spent while cclock wasn't running still
+// counts. When the session ran out in the meantime, its history record is
+// returned for the caller to keep, and the saved session is left for the
+// caller to clear.
+func (e *timerEngine) resume(state config.SessionState, profile config.Profile, now time.Time) (bool, *config.SessionRecord) {
+	*e = timerEngine{
+		profile:        profile,
+		running:        true,
+		start:          state.Start,
+		began:          state.Began,
+		paused:         state.Paused,
+		elapsed:        state.Elapsed(now),
+		phaseDurations: state.PhaseDurations,
+		phaseTimes:     state.PhaseTimes,
+		phaseEntered:   state.PhaseEntered,
+		log:            state.Log,
+		restored:       true,
+	}
+	if len(e.phaseDurations) != len(profile.Phases) {
+		// The profile was edited since, so its phases no longer line up
+		e.phaseDurations = phaseDurations(profile.Phases)
+		e.phaseTimes = e.phaseTimes[:min(len(e.phaseTimes), len(profile.Phases))]
+	}
+	if len(e.phaseTimes) > 0 {
+		// Carry on from the phase it was saved in. Sessions saved without
+		// their phase times are counted from the first phase.
+		e.phase = phaseAt(e.phaseDurations, e.phaseEntered)
+	}
+	if e.began.IsZero() {
+		// Saved by a version that didn't keep it
+		e.began = state.Start
+	}
+	before := *e
+	changed := e.updatePhase()
+	if record, ok := endedSession(before, *e, now); ok {
+		return changed, &record
+	}
+	return changed, nil
+}
+
+// handleResumeInput answers the prompt asking whether to resume the session
+// that was running when cclock last exited
+func (m model) handleResumeInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q", "esc":
+		return m, tea.Quit
+	case "y", "enter":
+		return m.resumeSession(*m.pendingSession)
+	case "n":
+		m.pendingSession = nil
+		config.ClearSession()
+	}
+	return m, nil
+}
+
+func (m model) resumeSession(state config.SessionState) (tea.Model, tea.Cmd) {
+	m.pendingSession = nil
+	timer := m.config.ProfileIndex(state.Profile)
+	if timer < 0 {
+		config.ClearSession()
+		return m, nil
+	}
+
+	changed, finished := m.timer.resume(state, m.config.Profiles[timer], time.Now())
+	m.timer.save()
+	if finished != nil {
+		// It ran out while cclock wasn't running
+		m = m.sessionEnded(*finished)
+	}
+	if changed {
+		return m, m.dingCmd()
+	}
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// statusFormat renders the timer for one kind of status bar
+type statusFormat struct {
+	// file renders the contents of a file the bar reads
+	file func(TimerOutput) string
+	// header is written to stdout once before the first line
+	header string
+	// line renders an update for a bar reading cclock's output line by line
+	line func(TimerOutput) string
+}
+
+// statusFormats are the status bar formats by name
+var statusFormats = map[string]statusFormat{
+	"waybar": {file: waybarOutput, line: waybarOutput},
+	// i3bar reads an endless JSON array of status lines when cclock is its
+	// status_command
+	"i3bar": {file: i3barOutput, header: "{\"version\":1}\n[\n", line: func(out TimerOutput) string {
+		return "[" + i3barOutput(out) + "],"
+	}},
+	// i3blocks reads three lines from a file, or JSON lines with
+	// format=json and interval=persist
+	"i3blocks": {file: i3blocksOutput, line: i3barOutput},
+	"polybar":  {file: polybarOutput, line: polybarOutput},
+}
+
+// classColors are the colors of the phase classes for bars that take colors
+// rather than classes
+var classColors = map[string]string{
+	"white":  "#FFFFFF",
+	"green":  "#00FF00",
+	"yellow": "#FFFF00",
+	"red":    "#FF0000",
+	"paused": "#888888",
+}
+
+func waybarOutput(out TimerOutput) string {
+	// Keep the tooltip markup readable rather than escaping < and >
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(out)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// i3barBlock is a block of the i3bar protocol
+type i3barBlock struct {
+	Name     string `json:"name"`
+	FullText string `json:"full_text"`
+	Color    string `json:"color"`
+}
+
+func i3barOutput(out TimerOutput) string {
+	data, _ := json.Marshal(i3barBlock{Name: "cclock", FullText: out.Text, Color: classColors[out.Class]})
+	return string(data)
+}
+
+func i3blocksOutput(out TimerOutput) string {
+	// full text, short text and color
+	return fmt.Sprintf("%s\n%s\n%s\n", out.Text, out.Text, classColors[out.Class])
+}
+
+func polybarOutput(out TimerOutput) string {
+	// Polybar reads % as the start of a format tag
+	text := strings.ReplaceAll(out.Text, "%", "%%")
+	return fmt.Sprintf("%%{F%s}%s%%{F-}", classColors[out.Class], text)
+}
+
+// statusSink is a status bar output being written to
+type statusSink struct {
+	format  statusFormat
+	path    string // file to write, or "-" for stdout
+	last    string // last line written to stdout
+	started bool   // whether the header was written to stdout
+}
+
+// write brings the sink up to date. Files are rewritten every time so a
+// removed file comes back, while stdout only gets a line when it changed.
+func (s *statusSink) write(out TimerOutput) error {
+	if s.path != "-" {
+		return os.WriteFile(s.path, []byte(s.format.file(out)), 0644)
+	}
+
+	line := s.format.line(out)
+	if s.started && line == s.last {
+		return nil
+	}
+	if !s.started {
+		if _, err := os.Stdout.WriteString(s.format.header); err != nil {
+			return err
+		}
+		s.started = true
+	}
+	s.last = line
+	_, err := fmt.Println(line)
+	return err
+}
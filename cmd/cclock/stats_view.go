@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/unquenchedservant/ChillClock/config"
+	util "github.com/unquenchedservant/ChillClock/utilities"
+)
+
+// Periods shown in the stats view
+const (
+	statsDays  = 7
+	statsWeeks = 4
+	statsDoses = 5 // best rated profile and amount pairs
+)
+
+// openStats switches to the stats view with the history as it is now
+func (m model) openStats() model {
+	m.mode = viewStats
+	m.historyError = ""
+	history, err := config.LoadHistory()
+	if err != nil {
+		m.historyError = err.Error()
+	}
+	m.history = history
+	return m
+}
+
+func (m model) handleStatsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "s":
+		m.mode = viewClock
+		m.history = nil
+	}
+	return m, nil
+}
+
+func (m model) renderStatsView() string {
+	var output strings.Builder
+	now := time.Now()
+
+	output.WriteString("\n")
+	output.WriteString(util.CenterText(util.GetYellowStyle().Bold(true).Render("Session History"), m.width))
+	output.WriteString("\n\n")
+
+	if m.historyError != "" {
+		output.WriteString(util.CenterText(util.GetRedStyle().Render("Error loading history: "+m.historyError), m.width))
+		output.WriteString("\n\n")
+	}
+
+	days := historyPeriods(m.history, now, statsDays, 1)
+	weeks := historyPeriods(m.history, now, statsWeeks, 7)
+	writeRow := func(label string, p periodStats, most int, style lipgloss.Style) {
+		average := ""
+		if p.sessions > 0 {
+			average = formatDuration(p.average())
+		}
+		line := fmt.Sprintf("%-14s %-20s %3d  %6s", label, statsBar(p.sessions, most, 20), p.sessions, average)
+		output.WriteString(util.CenterText(style.Render(line), m.width))
+		output.WriteString("\n")
+	}
+	header := fmt.Sprintf("%-14s %-20s %3s  %6s", "", "", "#", "Avg")
+
+	output.WriteString(util.CenterText(util.GetNormalStyle().Bold(true).Render(header), m.width))
+	output.WriteString("\n")
+	most := busiestPeriod(days)
+	for _, p := range days {
+		style := util.GetNormalStyle()
+		if p.start.Equal(dayStart(now)) {
+			style = util.GetGreenStyle()
+		}
+		writeRow(p.start.Format("Mon Jan 2"), p, most, style)
+	}
+	output.WriteString("\n")
+	most = busiestPeriod(weeks)
+	for _, p := range weeks {
+		style := util.GetNormalStyle()
+		if p.start.Equal(weekStart(now)) {
+			style = util.GetGreenStyle()
+		}
+		writeRow("Week of "+p.start.Format("Jan 2"), p, most, style)
+	}
+
+	if doses := historyDoses(m.history); len(doses) > 0 {
+		output.WriteString("\n")
+		header := fmt.Sprintf("%-20s %8s %3s  %6s", "Profile", "Amount", "#", "Rating")
+		output.WriteString(util.CenterText(util.GetNormalStyle().Bold(true).Render(header), m.width))
+		output.WriteString("\n")
+		for _, d := range doses[:min(len(doses), statsDoses)] {
+			rating := ""
+			if d.rated > 0 {
+				rating = fmt.Sprintf("%.1f/5", d.averageRating())
+			}
+			line := fmt.Sprintf("%-20s %8s %3d  %6s", truncate(d.profile, 20), formatGrams(d.grams)+"g", d.sessions, rating)
+			output.WriteString(util.CenterText(util.GetNormalStyle().Render(line), m.width))
+			output.WriteString("\n")
+		}
+	}
+
+	var all periodStats
+	for _, record := range m.history {
+		all.add(record)
+	}
+	output.WriteString("\n")
+	summary := "No sessions yet"
+	if all.sessions > 0 {
+		summary = fmt.Sprintf("All time: %d sessions, %d completed, %s on average", all.sessions, all.completed, formatDuration(all.average()))
+	}
+	output.WriteString(util.CenterText(util.GetYellowStyle().Render(summary), m.width))
+	output.WriteString("\n\n")
+	output.WriteString(util.CenterText(util.GetNormalStyle().Render("Esc/q/s: Exit"), m.width))
+
+	return output.String()
+}
+
+// statsBar draws count as a bar that is width long at most
+func statsBar(count, most, width int) string {
+	if count == 0 || most == 0 {
+		return ""
+	}
+	return strings.Repeat("█", max(count*width/most, 1))
+}
+
+// busiestPeriod returns the most sessions in any of the periods
+func busiestPeriod(periods []periodStats) int {
+	most := 0
+	for _, p := range periods {
+		most = max(most, p.sessions)
+	}
+	return most
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
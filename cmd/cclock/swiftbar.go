@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+	"github.com/unquenchedservant/ChillClock/control"
+)
+
+// runSwiftBar prints the timer as a SwiftBar or xbar plugin: the time as the
+// menu bar title and a dropdown whose items call back into cclock
+func runSwiftBar(args []string) error {
+	flags := flag.NewFlagSet("swiftbar", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cclock swiftbar\n\nPrint the timer in the SwiftBar and xbar plugin format.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	resp, err := control.Send(control.Request{Command: "status"})
+	if errors.Is(err, control.ErrNotRunning) {
+		// The plugin is still shown, it just can't do anything yet
+		fmt.Println("0:00 | color=white")
+		fmt.Println("---")
+		fmt.Println("cclock isn't running, start cclock or cclock daemon")
+		return nil
+	} else if err != nil {
+		return err
+	} else if resp.Status == nil {
+		return errors.New(resp.Error)
+	}
+
+	fmt.Print(formatSwiftBar(*resp.Status, cfg, exe))
+	return nil
+}
+
+// formatSwiftBar returns the plugin output for a status. exe is the cclock
+// binary the menu items run.
+func formatSwiftBar(status control.Status, cfg config.Config, exe string) string {
+	var b strings.Builder
+	action := func(title string, args ...string) {
+		// Quoted, as SwiftBar and xbar split parameters at spaces
+		fmt.Fprintf(&b, "%s | bash=%q", swiftBarText(title), exe)
+		for i, arg := range args {
+			fmt.Fprintf(&b, " param%d=%q", i+1, arg)
+		}
+		b.WriteString(" terminal=false refresh=true\n")
+	}
+
+	elapsed := formatDuration(time.Duration(status.ElapsedSeconds) * time.Second)
+	switch status.State {
+	case "running":
+		fmt.Fprintf(&b, "%s | color=%s\n", elapsed, phaseClass(timerPhase(status.Phase), status.PhaseCount))
+	case "paused":
+		fmt.Fprintf(&b, "PAUSED %s | color=gray\n", elapsed)
+	default:
+		b.WriteString("0:00 | color=white\n")
+	}
+	b.WriteString("---\n")
+
+	if status.State == "running" || status.State == "paused" {
+		fmt.Fprintf(&b, "%s\n", swiftBarText(formatStatus(status)))
+		if status.State == "paused" {
+			action("Resume", "resume")
+		} else {
+			action("Pause", "pause")
+		}
+		action("Next phase", "next")
+		action("Back a phase", "back")
+		action("Add 30 seconds", "extend", "30")
+		action("Stop", "stop")
+		return b.String()
+	}
+
+	for i, profile := range cfg.Profiles {
+		title := fmt.Sprintf("Start %s (%dm)", profile.Name, config.TotalMinutes(profile.Phases))
+		if profile.Name == cfg.DefaultProfile {
+			title += " - default"
+		}
+		// Profiles are started by number so names don't need quoting
+		action(title, "start", fmt.Sprint(i+1))
+	}
+	return b.String()
+}
+
+// swiftBarText keeps text from being read as plugin parameters
+func swiftBarText(text string) string {
+	return strings.ReplaceAll(text, "|", "/")
+}
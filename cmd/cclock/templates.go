@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/unquenchedservant/ChillClock/config"
+	util "github.com/unquenchedservant/ChillClock/utilities"
+)
+
+// timerData is what the text templates can use
+type timerData struct {
+	Profile        string
+	Elapsed        string // m:ss since the session started
+	Remaining      string // m:ss left in the session
+	Total          string // m:ss the session runs for
+	PhaseRemaining string // m:ss left in the current phase
+	PhaseEnd       string // m:ss into the session when the current phase ends
+	Phase          int    // from 1
+	PhaseCount     int
+	PhaseName      string
+	Temp           int
+	NextPhaseName  string // empty in the last phase
+	NextTemp       int    // 0 in the last phase
+	Paused         bool
+	Completed      bool
+}
+
+// newTimerData collects the template data for the timer
+func newTimerData(e timerEngine) timerData {
+	phases := e.profile.Phases
+	current := int(e.phase) - 1
+	total := totalDuration(e.phaseDurations)
+	phaseEnd := phaseStart(e.phaseDurations, e.phase+1)
+	data := timerData{
+		Profile:        e.profile.Name,
+		Elapsed:        formatDuration(e.elapsed),
+		Remaining:      formatDuration(max(total-e.elapsed, 0)),
+		Total:          formatDuration(total),
+		PhaseRemaining: formatDuration(max(phaseEnd-e.elapsed, 0)),
+		PhaseEnd:       formatDuration(phaseEnd),
+		Phase:          max(int(e.phase), 0),
+		PhaseCount:     len(phases),
+		Temp:           e.temp(),
+		Paused:         e.paused,
+		Completed:      e.phase == phaseCompleted,
+	}
+	if current >= 0 {
+		data.PhaseName = config.PhaseName(phases, current)
+	}
+	if next := current + 1; current >= 0 && next < len(phases) {
+		data.NextPhaseName = config.PhaseName(phases, next)
+		data.NextTemp = phases[next].Temp
+	}
+	return data
+}
+
+// textTemplates are the parsed templates from the config. A nil template
+// keeps the built-in text.
+type textTemplates struct {
+	statusBar         *template.Template
+	timerLine         *template.Template
+	notificationTitle *template.Template
+	notificationBody  *template.Template
+}
+
+// parseTemplates parses the templates in the config
+func parseTemplates(t config.Templates) (textTemplates, error) {
+	var texts textTemplates
+	for _, tmpl := range []struct {
+		name, text string
+		parsed     **template.Template
+	}{
+		{"status_bar", t.StatusBar, &texts.statusBar},
+		{"timer_line", t.TimerLine, &texts.timerLine},
+		{"notification_title", t.NotificationTitle, &texts.notificationTitle},
+		{"notification_body", t.NotificationBody, &texts.notificationBody},
+	} {
+		if tmpl.text == "" {
+			continue
+		}
+		parsed, err := template.New(tmpl.name).Parse(tmpl.text)
+		if err == nil {
+			// Catch unknown fields now rather than on the first phase change
+			err = parsed.Execute(io.Discard, timerData{})
+		}
+		if err != nil {
+			return texts, fmt.Errorf("Error in the %s template: %w", tmpl.name, err)
+		}
+		*tmpl.parsed = parsed
+	}
+	return texts, nil
+}
+
+// render executes a template with the timer's data, or returns fallback when
+// the template isn't set or fails
+func render(tmpl *template.Template, e timerEngine, fallback string) string {
+	if tmpl == nil {
+		return fallback
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newTimerData(e)); err != nil {
+		return fallback
+	}
+	return b.String()
+}
+
+// notification returns the notification for the phase the timer just
+// entered, or an empty notification when there is nothing to announce
+func (t textTemplates) notification(e timerEngine) util.Notification {
+	var n util.Notification
+	switch {
+	case e.phase == phaseCompleted:
+		n = util.Notification{Title: "Timer Complete", Body: "All phases finished!"}
+	case e.phase > phaseNotStarted:
+		n = util.Notification{Title: fmt.Sprintf("Phase %d", e.phase), Body: fmt.Sprintf("%d°", e.temp())}
+	default:
+		return n
+	}
+	n.Title = render(t.notificationTitle, e, n.Title)
+	n.Body = render(t.notificationBody, e, n.Body)
+	if e.running {
+		n.Actions = notificationActions
+	}
+	return n
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// terminalTitle is the terminal title for the timer, the same text as the
+// timer line on the clock
+func (m model) terminalTitle() string {
+	if m.timer.idle() {
+		return "cclock"
+	}
+	return m.timerText()
+}
+
+// updateTerminalTitle sets the terminal title when it changed since the last
+// update, so the title isn't rewritten on every tick
+func (m model) updateTerminalTitle() (model, tea.Cmd) {
+	if !m.config.TerminalTitle {
+		return m, nil
+	}
+	title := m.terminalTitle()
+	if title == m.windowTitle {
+		return m, nil
+	}
+	m.windowTitle = title
+	return m, tea.SetWindowTitle(title)
+}
+
+// progressSequence returns the OSC 9;4 sequence that shows the progress of
+// the session on the terminal tab or taskbar. The state colors the bar: normal
+// (green) for the first phase, warning (yellow) for the middle phases and
+// while paused, and error (red) for the last phase.
+func progressSequence(e timerEngine) string {
+	if e.idle() {
+		return "\x1b]9;4;0;0\x07"
+	}
+	total := totalDuration(e.phaseDurations)
+	percent := 0
+	if total > 0 {
+		percent = min(int(e.elapsed*100/total), 100)
+	}
+	state := 4
+	switch {
+	case e.paused:
+	case phaseClass(e.phase, len(e.profile.Phases)) == "green":
+		state = 1
+	case phaseClass(e.phase, len(e.profile.Phases)) == "red":
+		state = 2
+	}
+	return fmt.Sprintf("\x1b]9;4;%d;%d\x07", state, percent)
+}
+
+// resetTerminal clears the title and progress the clock set, once the program
+// has finished drawing
+func (m model) resetTerminal() {
+	if m.config.TerminalTitle {
+		os.Stdout.WriteString("\x1b]2;\x07")
+	}
+	if m.config.TerminalProgress {
+		os.Stdout.WriteString(progressSequence(timerEngine{}))
+	}
+}
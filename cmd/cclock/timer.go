@@ -1,198 +1,189 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"html"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/unquenchedservant/ChillClock/config"
 	util "github.com/unquenchedservant/ChillClock/utilities"
 )
 
 func (m model) handleTick() (tea.Model, tea.Cmd) {
-	if m.timerRunning {
-		m.timerElapsed = time.Since(m.timerStart)
-		phase1Dur := time.Duration(0)
-		phase2Dur := time.Duration(0)
-		phase3Dur := time.Duration(0)
-		if m.timer == TIMER_1 {
-			phase1Dur = time.Duration(m.config.Timer.Phase1Duration_Timer1) * time.Minute
-			phase2Dur = time.Duration(m.config.Timer.Phase2Duration_Timer1) * time.Minute
-			phase3Dur = time.Duration(m.config.Timer.Phase3Duration_Timer1) * time.Minute
-		}else if m.timer == TIMER_2 {
-			phase1Dur = time.Duration(m.config.Timer.Phase1Duration_Timer2) * time.Minute
-			phase2Dur = time.Duration(m.config.Timer.Phase2Duration_Timer2) * time.Minute
-			phase3Dur = time.Duration(m.config.Timer.Phase3Duration_Timer2) * time.Minute
-		}
-		totalDur := phase1Dur + phase2Dur + phase3Dur
-
-		oldPhase := m.currentPhase
-		if m.timerElapsed >= totalDur {
-			m.currentPhase = phaseCompleted
-			m.timerRunning = false
-		} else if m.timerElapsed >= phase1Dur+phase2Dur {
-			m.currentPhase = phase3
-		} else if m.timerElapsed >= phase1Dur {
-			m.currentPhase = phase2
-		} else {
-			m.currentPhase = phase1
-		}
+	if time.Since(m.breakChecked) > breakCheckInterval {
+		m = m.refreshBreak()
+	}
+	if m.timer.tick(time.Now()) {
+		m.timer.save()
+		m.files.writeTimerState(m.timer, m.texts)
+		return m, tea.Batch(tickCmd(), m.dingCmd())
+	}
+	m.files.writeTimerState(m.timer, m.texts)
+	return m, tickCmd()
+}
 
-		writeTimerState(m)
-
-		if oldPhase != m.currentPhase && m.currentPhase != phaseNotStarted {
-			var temp int
-			switch m.currentPhase {
-			case phase1:
-				if m.timer == TIMER_1{
-					temp = m.config.Timer.Phase1Temp_Timer1
-				}else if m.timer == TIMER_2 {
-					temp = m.config.Timer.Phase1Temp_Timer2
-				}
-			case phase2:
-				if m.timer == TIMER_1 {
-					temp = m.config.Timer.Phase2Temp_Timer1
-				}else if m.timer == TIMER_2 {
-					temp = m.config.Timer.Phase2Temp_Timer2
-				}
-			case phase3:
-				if m.timer == TIMER_1 {
-					temp = m.config.Timer.Phase3Temp_Timer1
-				}else if m.timer == TIMER_2 {
-					temp = m.config.Timer.Phase3Temp_Timer2
-				}
-			case phaseCompleted:
-				temp = 0
-			}
-			return m, tea.Batch(tickCmd(), dingCmd(m.currentPhase, temp))
-		}
-	} else {
-		writeTimerState(m)
+// dingCmd rings and notifies for the phase the timer is in now
+func (m model) dingCmd() tea.Cmd {
+	return dingCmd(m.texts.notification(m.timer), m.notifier)
+}
+
+// profile returns the profile at the given index
+func (m model) profile(timer int) config.Profile {
+	if timer < 0 || timer >= len(m.config.Profiles) {
+		return config.Profile{}
+	}
+	return m.config.Profiles[timer]
+}
+
+// phases returns the configured phases of the profile at the given index
+func (m model) phases(timer int) []config.Phase {
+	return m.profile(timer).Phases
+}
+
+// formatDuration formats a duration as minutes and seconds, e.g. 3:07
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// phaseClass returns the status bar class of a phase. The first phase is
+// green, the last is red and everything in between is yellow.
+func phaseClass(phase timerPhase, count int) string {
+	switch {
+	case phase < 1:
+		return "white"
+	case phase == 1:
+		return "green"
+	case int(phase) == count:
+		return "red"
+	default:
+		return "yellow"
 	}
-	return m, tea.Batch(tickCmd(), watchForFileClick())
+}
+
+func phaseStyle(phase timerPhase, count int) lipgloss.Style {
+	switch phaseClass(phase, count) {
+	case "green":
+		return util.GetGreenStyle()
+	case "yellow":
+		return util.GetYellowStyle()
+	case "red":
+		return util.GetRedStyle()
+	}
+	return util.GetNormalStyle()
 }
 
 func (m model) getTimerDisplay() (string, lipgloss.Style) {
+	if m.pendingSession != nil {
+		state := m.pendingSession
+		line1 := util.CenterText(fmt.Sprintf("A %s session started at %s was still running (%s elapsed)",
+			state.Profile, state.Start.Format("15:04"), formatDuration(state.Elapsed(time.Now()))), m.width)
+		line2 := util.CenterText("Resume it? (y)es / (n)o", m.width)
+		return line1 + "\n" + line2, util.GetYellowStyle()
+	}
 
-	if (!m.timerRunning && m.currentPhase == phaseNotStarted) || m.currentPhase == phaseCompleted {
-		currentDefault := ""
-		if m.timerDefault == TIMER_1{
-			duration := m.config.Timer.Phase1Duration_Timer1 + m.config.Timer.Phase2Duration_Timer1 + m.config.Timer.Phase3Duration_Timer1
-			currentDefault = fmt.Sprintf("Timer 1 (%dm)", duration)
-		}
-		if m.timerDefault == TIMER_2{
-			duration := m.config.Timer.Phase1Duration_Timer2 + m.config.Timer.Phase2Duration_Timer2 + m.config.Timer.Phase3Duration_Timer2
-			currentDefault = fmt.Sprintf("Timer 2 (%dm)", duration)
-		}
-		line1 := util.CenterText("Press Enter or Space to start default timer, '?' for config", m.width)
-		line2 := util.CenterText("'1|2' to start respective timer", m.width)
+	if m.pendingStart != nil {
+		line1 := util.CenterText(m.pendingStart.warning, m.width)
+		line2 := util.CenterText("Start anyway? (y)es / (n)o", m.width)
+		return line1 + "\n" + line2, util.GetYellowStyle()
+	}
+
+	if m.timer.idle() {
+		profile := m.profile(m.timerDefault)
+		currentDefault := fmt.Sprintf("%s (%dm)", profile.Name, config.TotalMinutes(profile.Phases))
+		line1 := util.CenterText("Press Enter or Space to start default timer, '?' for config, 's' for stats", m.width)
+		line2 := util.CenterText(fmt.Sprintf("'1-%d' to start respective timer", min(len(m.config.Profiles), 9)), m.width)
 		line3 := util.CenterText("(d)efault timer: " + currentDefault, m.width)
+		if now := time.Now(); now.Before(m.limitUntil) {
+			line4 := util.CenterText(util.GetYellowStyle().Render(limitMessage(m.limitUntil, m.limitReason, now)), m.width)
+			return line1 + "\n" + line2 + "\n" + line3 + "\n" + line4, util.GetNormalStyle()
+		}
 		return line1 + "\n" + line2 + "\n" + line3, util.GetNormalStyle()
 	}
 
-	elapsed := m.timerElapsed
-	minutes := int(elapsed.Minutes())
-	seconds := int(elapsed.Seconds()) % 60
-	duration := 0
-	if m.timer == TIMER_1 {
-		duration = m.config.Timer.Phase1Duration_Timer1 + m.config.Timer.Phase2Duration_Timer1 + m.config.Timer.Phase3Duration_Timer1
-	}else if m.timer == TIMER_2 {
-		duration = m.config.Timer.Phase1Duration_Timer2 + m.config.Timer.Phase2Duration_Timer2 + m.config.Timer.Phase3Duration_Timer2
-	}
-	timerText := fmt.Sprintf("Timer: %d:%02d (%d:00)", minutes, seconds, duration)
-
-	var style lipgloss.Style
-	var temp int
-	switch m.currentPhase {
-	case phase1:
-		style = util.GetGreenStyle()
-		if m.timer == TIMER_1 {
-			temp = m.config.Timer.Phase1Temp_Timer1
-		}else if m.timer == TIMER_2{
-			temp = m.config.Timer.Phase1Temp_Timer2
-		}
-	case phase2:
-		style = util.GetYellowStyle()
-		if m.timer == TIMER_1{
-			temp = m.config.Timer.Phase2Temp_Timer1
-		}else if m.timer == TIMER_2{
-			temp = m.config.Timer.Phase2Temp_Timer2
-		}
-	case phase3:
-		style = util.GetRedStyle()
-		if m.timer == TIMER_1{
-			temp = m.config.Timer.Phase3Temp_Timer1
-		}else if m.timer == TIMER_2{
-			temp = m.config.Timer.Phase3Temp_Timer2
-		}
-	default:
+	style := phaseStyle(m.timer.phase, len(m.timer.profile.Phases))
+	helpText := "(p)ause | (n)ext/(b)ack phase | +: 30s | (m)inute | Enter/Space: Stop"
+	if m.timer.paused {
 		style = util.GetNormalStyle()
+		helpText = "(p) resume | (n)ext/(b)ack phase | +: 30s | (m)inute | Enter/Space: Stop"
 	}
-	timerText += fmt.Sprintf(" Temp: %d°", temp)
-	line := util.CenterText(timerText, m.width)
-	return line, style
+	line := util.CenterText(m.timerText(), m.width)
+	line2 := util.CenterText(util.GetNormalStyle().Render(helpText), m.width)
+	return line + "\n" + line2, style
 }
 
-func writeTimerState(m model) error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
-
-	timerFile := filepath.Join(homeDir, "dhv_timer.txt")
-	var output TimerOutput
-
-	if (!m.timerRunning && m.currentPhase == phaseNotStarted) || m.currentPhase == phaseCompleted {
-		output = TimerOutput{Text: "0:00", Class: "white"}
-	} else {
-		minutes := int(m.timerElapsed.Minutes())
-		seconds := int(m.timerElapsed.Seconds()) % 60
-		timerText := fmt.Sprintf("%d:%02d", minutes, seconds)
-
-		var class string
-		switch m.currentPhase {
-		case phase1:
-			class = "green"
-		case phase2:
-			class = "yellow"
-		case phase3:
-			class = "red"
-		default:
-			class = "white"
-		}
-
-		output = TimerOutput{Text: timerText, Class: class}
+// timerText is the line describing the running timer
+func (m model) timerText() string {
+	profile := m.timer.profile
+	elapsed := formatDuration(m.timer.elapsed)
+	duration := formatDuration(totalDuration(m.timer.phaseDurations))
+	timerText := fmt.Sprintf("%s: %s (%s)", profile.Name, elapsed, duration)
+	if m.timer.paused {
+		timerText = fmt.Sprintf("%s: PAUSED %s (%s)", profile.Name, elapsed, duration)
 	}
+	phaseEnd := formatDuration(phaseStart(m.timer.phaseDurations, m.timer.phase+1))
+	timerText += fmt.Sprintf(" Phase %d/%d until %s Temp: %d°", m.timer.phase, len(profile.Phases), phaseEnd, m.timer.temp())
+	return render(m.texts.timerLine, m.timer, timerText)
+}
 
-	data, err := json.Marshal(output)
-	if err != nil {
-		return err
+// timerOutput describes the timer for the status bar
+func timerOutput(e timerEngine, texts textTemplates) TimerOutput {
+	if e.idle() {
+		return TimerOutput{Text: "0:00", Class: "white", Alt: "idle", Tooltip: "No timer running"}
 	}
 
-	return os.WriteFile(timerFile, data, 0644)
+	phases := e.profile.Phases
+	total := totalDuration(e.phaseDurations)
+	output := TimerOutput{
+		Text:    formatDuration(e.elapsed),
+		Class:   phaseClass(e.phase, len(phases)),
+		Alt:     config.PhaseName(phases, int(e.phase)-1),
+		Tooltip: timerTooltip(e),
+	}
+	if total > 0 {
+		output.Percentage = min(int(e.elapsed*100/total), 100)
+	}
+	if e.paused {
+		output.Text = "PAUSED " + output.Text
+		output.Class = "paused"
+	}
+	output.Text = render(texts.statusBar, e, output.Text)
+	return output
 }
 
-func watchForFileClick() tea.Cmd {
-	return func() tea.Msg {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil
-		}
-
-		clickFile := filepath.Join(homeDir, "dhv_timer_click1")
-		if _, err := os.Stat(clickFile); err == nil {
-			os.Remove(clickFile)
-			return fileClickMsg{}
-		}
+// timerTooltip lists the profile, the current phase and what comes next
+func timerTooltip(e timerEngine) string {
+	phases := e.profile.Phases
+	current := int(e.phase) - 1
+	phaseEnd := phaseStart(e.phaseDurations, e.phase+1)
+	phaseLine := fmt.Sprintf("Phase %d/%d at %d°", e.phase, len(phases), e.temp())
+	if phases[current].Name != "" {
+		phaseLine = html.EscapeString(phases[current].Name) + ", p" + phaseLine[1:]
+	}
+	lines := []string{"<b>" + html.EscapeString(e.profile.Name) + "</b>", phaseLine}
+	if next := current + 1; next < len(phases) {
+		lines = append(lines, fmt.Sprintf("Next: %s at %d° in %s",
+			html.EscapeString(config.PhaseName(phases, next)), phases[next].Temp, formatDuration(phaseEnd-e.elapsed)))
+	}
+	remaining := max(totalDuration(e.phaseDurations)-e.elapsed, 0)
+	lines = append(lines, formatDuration(remaining)+" remaining")
+	if e.paused {
+		lines = append(lines, "Paused")
+	}
+	return strings.Join(lines, "\n")
+}
 
-		clickFile2 := filepath.Join(homeDir, "dhv_timer_click2")
-		if _, err := os.Stat(clickFile2); err == nil {
-			os.Remove(clickFile2)
-			return fileClickMsg2{}
+// writeTimerState writes the timer to every status bar sink
+func (f statusFiles) writeTimerState(e timerEngine, texts textTemplates) error {
+	output := timerOutput(e, texts)
+	var errs []error
+	for _, sink := range f.sinks {
+		if err := sink.write(output); err != nil {
+			errs = append(errs, err)
 		}
-		return nil
 	}
-}
\ No newline at end of file
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/control"
+)
+
+// runTmux prints the timer as a tmux status line segment
+func runTmux(args []string) error {
+	flags := flag.NewFlagSet("tmux", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cclock tmux\n\nPrint the timer for the tmux status line, e.g. set -g status-right '#(cclock tmux)'\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	resp, err := control.Send(control.Request{Command: "status"})
+	if errors.Is(err, control.ErrNotRunning) {
+		// Nothing to show, and an error would end up in the status line
+		return nil
+	} else if err != nil {
+		return err
+	} else if resp.Status == nil {
+		return errors.New(resp.Error)
+	}
+	fmt.Println(formatTmux(*resp.Status))
+	return nil
+}
+
+// formatTmux returns the status line segment for a status, e.g.
+// "#[fg=green]3:12 350°#[default]". It's empty while no timer runs.
+func formatTmux(status control.Status) string {
+	elapsed := formatDuration(time.Duration(status.ElapsedSeconds) * time.Second)
+	switch status.State {
+	case "running":
+		color := phaseClass(timerPhase(status.Phase), status.PhaseCount)
+		return fmt.Sprintf("#[fg=%s]%s %d°#[default]", color, elapsed, status.Temp)
+	case "paused":
+		return fmt.Sprintf("#[fg=colour244]PAUSED %s %d°#[default]", elapsed, status.Temp)
+	}
+	return ""
+}
+
+// refreshTmux redraws the status line of every tmux client, so a phase
+// change shows straight away rather than on tmux's next interval
+func refreshTmux() {
+	out, err := exec.Command("tmux", "list-clients", "-F", "#{client_name}").Output()
+	if err != nil {
+		// No tmux server, or no tmux at all
+		return
+	}
+	for _, client := range strings.Fields(string(out)) {
+		exec.Command("tmux", "refresh-client", "-S", "-t", client).Run()
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Trigger files are touched in the trigger dir by status bar click handlers.
+// Touching dhv_timer_click1 toggles the default profile, dhv_timer_click2 the
+// profile after it and so on. Touching dhv_timer_pause pauses or resumes the
+// running timer.
+const (
+	clickFilePrefix = "dhv_timer_click"
+	pauseFileName   = "dhv_timer_pause"
+)
+
+// pollInterval is how often trigger files are looked for where the kernel
+// can't tell us about them
+const pollInterval = 250 * time.Millisecond
+
+// triggerMsg returns the message a trigger file sends, or nil when name isn't
+// a trigger file
+func triggerMsg(name string) tea.Msg {
+	if name == pauseFileName {
+		return pauseClickMsg{}
+	}
+	if number, ok := strings.CutPrefix(name, clickFilePrefix); ok {
+		if n, err := strconv.Atoi(number); err == nil && n >= 1 {
+			return fileClickMsg{offset: n - 1}
+		}
+	}
+	return nil
+}
+
+// claimTrigger removes a touched trigger file and sends its message. Only
+// the call that manages to remove the file sends, so a touch that the watcher
+// hears about more than once still counts once.
+func claimTrigger(dir, name string, send func(tea.Msg)) {
+	msg := triggerMsg(name)
+	if msg == nil {
+		return
+	}
+	if err := os.Remove(filepath.Join(dir, name)); err != nil {
+		return
+	}
+	send(msg)
+}
+
+// scanTriggers claims every trigger file currently in dir
+func scanTriggers(dir string, send func(tea.Msg)) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		claimTrigger(dir, entry.Name(), send)
+	}
+}
+
+// pollTriggers looks for trigger files in dir every pollInterval until done
+// is closed
+func pollTriggers(dir string, send func(tea.Msg), done <-chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		scanTriggers(dir, send)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
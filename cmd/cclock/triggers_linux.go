@@ -0,0 +1,62 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/sys/unix"
+)
+
+// watchTriggers sends the message of every trigger file touched in dir until
+// done is closed. It sleeps on inotify, and only falls back to polling when
+// inotify isn't available.
+func watchTriggers(dir string, send func(tea.Msg), done <-chan struct{}) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		pollTriggers(dir, send, done)
+		return
+	}
+	// Wrapping the non-blocking descriptor in a file parks reads in the
+	// runtime's poller, and lets Close wake up a pending read
+	events := os.NewFile(uintptr(fd), "inotify")
+	defer events.Close()
+
+	const mask = unix.IN_CREATE | unix.IN_ATTRIB | unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO
+	if _, err := unix.InotifyAddWatch(fd, dir, mask); err != nil {
+		events.Close()
+		pollTriggers(dir, send, done)
+		return
+	}
+
+	go func() {
+		<-done
+		events.Close()
+	}()
+
+	// Pick up anything touched before we started watching
+	scanTriggers(dir, send)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := events.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// Events were dropped, so look at what's there instead
+				scanTriggers(dir, send)
+				continue
+			}
+			name := string(bytes.TrimRight(buf[nameStart:offset], "\x00"))
+			claimTrigger(dir, name, send)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// watchTriggers sends the message of every trigger file touched in dir until
+// done is closed. Without inotify the directory is polled.
+func watchTriggers(dir string, send func(tea.Msg), done <-chan struct{}) {
+	pollTriggers(dir, send, done)
+}
@@ -1,20 +1,21 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	util "github.com/unquenchedservant/ChillClock/utilities"
 )
 
+// timerPhase is the phase of the running timer. Phases are numbered from 1 up
+// to the number of phases in the timer's config.
 type timerPhase int
 
 const (
-	phaseNotStarted timerPhase = iota
-	phase1
-	phase2
-	phase3
-	phaseCompleted
+	phaseNotStarted timerPhase = timerPhase(util.PhaseNotStarted)
+	phaseCompleted  timerPhase = timerPhase(util.PhaseCompleted)
 )
 
 type viewMode int
@@ -22,46 +23,83 @@ type viewMode int
 const (
 	viewClock viewMode = iota
 	viewConfig
+	viewStats
+	viewLog
 )
 
+// configField is a row on a config page. The profile name comes first, then
+// the phase durations and then the phase temperatures.
 type configField int
 
-const (
-	fieldPhase1DurationT1 configField = iota
-	fieldPhase2DurationT1
-	fieldPhase3DurationT1
-	fieldPhase1TempT1
-	fieldPhase2TempT1
-	fieldPhase3TempT1
-	fieldPhase1DurationT2
-	fieldPhase2DurationT2
-	fieldPhase3DurationT2
-	fieldPhase1TempT2
-	fieldPhase2TempT2
-	fieldPhase3TempT2
-	fieldMax
-)
-
 type tickMsg time.Time
-type dingMsg struct{}
-type fileClickMsg struct{}
-type fileClickMsg2 struct{}
+// dingMsg is sent once a notification went out. sequence is set when it goes
+// to the terminal: the escape sequence to send with the next frame, while the
+// clock flashes to draw attention to it. err is set when something failed.
+type dingMsg struct {
+	notification util.Notification
+	sequence     string
+	err          error
+}
+// flashSentMsg is sent once the notification sequence had time to be drawn,
+// so it can be dropped from the view
+type flashSentMsg struct {
+	sequence string
+}
+
+// fileClickMsg is sent when a click file is touched. offset is the position of
+// the profile to toggle counting from the default profile.
+type fileClickMsg struct {
+	offset int
+}
+type pauseClickMsg struct{}
 
+// TimerOutput is the timer as shown on a status bar, in Waybar's custom
+// module format
 type TimerOutput struct {
-	Text  string `json:"text"`
-	Class string `json:"class"`
+	Text       string `json:"text"`
+	Class      string `json:"class"`
+	Alt        string `json:"alt"`        // phase name, or idle
+	Tooltip    string `json:"tooltip"`    // Pango markup
+	Percentage int    `json:"percentage"` // progress through the whole session
 }
 
+// tickInterval is how often the timer is brought up to date
+const tickInterval = time.Second / 10
+
 func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second / 10, func(t time.Time) tea.Msg {
+	return tea.Tick(tickInterval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
-func dingCmd(phase timerPhase, temp int) tea.Cmd {
+func dingCmd(n util.Notification, notifier util.NotifierChain) tea.Cmd {
 	return func() tea.Msg {
-		util.PlayBeep()
-		util.SendNotification(util.TimerPhase(phase), temp)
-		return dingMsg{}
+		used, err := ding(n, notifier)
+		msg := dingMsg{notification: n, err: err}
+		if terminal, ok := used.(util.TerminalNotifier); ok {
+			// Written with the view, so it can't land in the middle of a frame
+			msg.sequence, _ = terminal.Sequence(n)
+		}
+		return msg
+	}
+}
+
+// ding plays the beep and sends the notification for a new phase through the
+// first notifier that works. It returns that notifier, and what went wrong
+// when all of the notifiers failed. The beep's failure is only reported with
+// theirs, as there's often no sound player where a notifier still works, e.g.
+// over SSH.
+func ding(n util.Notification, notifier util.NotifierChain) (util.Notifier, error) {
+	if n.Title == "" {
+		return nil, nil
 	}
-}
\ No newline at end of file
+	beepErr := util.PlayBeep()
+	used, err := notifier.Send(n)
+	if err == nil {
+		return used, nil
+	}
+	if beepErr != nil {
+		err = errors.Join(fmt.Errorf("beep: %w", beepErr), err)
+	}
+	return used, err
+}
@@ -1,38 +1,98 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/unquenchedservant/ChillClock/config"
 )
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd := m.update(msg)
+	if record, ok := endedSession(m.timer, updated.(model).timer, time.Now()); ok {
+		updated = updated.(model).sessionEnded(record)
+	}
+	if sessionStarted(m.timer, updated.(model).timer) {
+		breakBroken(m.timer, updated.(model).timer, time.Now())
+		updated = updated.(model).refreshBreak()
+		if m.config.LogPrompt == "start" {
+			updated = updated.(model).openLog(nil)
+		}
+	}
+	if m.server != nil {
+		announce(m.server, m.config, timerEvents(m.timer, updated.(model).timer))
+	}
+	updated, titleCmd := updated.(model).updateTerminalTitle()
+	return updated, tea.Batch(cmd, titleCmd)
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if m.mode == viewConfig{
 			return m.handleConfigInput(msg)
 		}
+		if m.mode == viewStats {
+			return m.handleStatsInput(msg)
+		}
+		if m.mode == viewLog {
+			return m.handleLogInput(msg)
+		}
+		if m.pendingSession != nil {
+			return m.handleResumeInput(msg)
+		}
+		if m.pendingStart != nil {
+			return m.handleStartConfirmInput(msg)
+		}
 		return m.handleClockInput(msg)
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 	case fileClickMsg:
-		if m.timerDefault == TIMER_1 {
-			return m.handleTimerToggle(TIMER_1), watchForFileClick()
-		}else if m.timerDefault == TIMER_2 {
-			return m.handleTimerToggle(TIMER_2), watchForFileClick()
-		}
-	case fileClickMsg2:
-		if m.timerDefault == TIMER_1 {
-			return m.handleTimerToggle(TIMER_2), watchForFileClick()
-		}else if m.timerDefault == TIMER_2 {
-			return m.handleTimerToggle(TIMER_1), watchForFileClick()
-		}
-		return m.handleTimerToggle(TIMER_2), watchForFileClick()
+		if msg.offset >= len(m.config.Profiles) {
+			return m, nil
+		}
+		timer := (m.timerDefault + msg.offset) % len(m.config.Profiles)
+		// A click starts a new session, which answers the resume prompt
+		m.pendingSession = nil
+		return m.handleTimerToggle(timer)
+	case pauseClickMsg:
+		return m.handlePauseToggle(), nil
+	case controlCall:
+		resp, phaseChanged := handleRequest(&m.timer, m.config, msg.request, time.Now())
+		msg.reply <- resp
+		if m.timer.running {
+			// Starting from the outside answers the prompts too
+			m.pendingSession = nil
+			m.pendingStart = nil
+		}
+		m.files.writeTimerState(m.timer, m.texts)
+		if phaseChanged {
+			return m, m.dingCmd()
+		}
 	case tickMsg:
 		return m.handleTick()
 	case dingMsg:
+		m.notifyError = ""
+		if msg.err != nil {
+			// Each backend's failure is on a line of its own
+			m.notifyError = strings.ReplaceAll(msg.err.Error(), "\n", "; ")
+		}
+		if msg.sequence != "" {
+			m.flash = msg.notification
+			m.flashSequence = msg.sequence
+			m.flashUntil = time.Now().Add(flashDuration)
+			return m, tea.Tick(flashSequenceHold, func(time.Time) tea.Msg {
+				return flashSentMsg{sequence: msg.sequence}
+			})
+		}
 		return m, nil
+	case flashSentMsg:
+		if m.flashSequence == msg.sequence {
+			m.flashSequence = ""
+		}
 	}
 	return m, nil
 }
@@ -42,50 +102,101 @@ func (m model) handleClockInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+c", "q", "esc":
 		return m, tea.Quit
 	case "?":
-		if !m.timerRunning {
+		if !m.timer.running {
 			m.mode = viewConfig
-			m.selectedField = fieldPhase1DurationT1
+			m.selectedField = 0
+			m.configPage = m.timerDefault
 			m.editingField = false
 			m.inputBuffer = ""
 		}
+	case "s":
+		if m.pendingSession == nil {
+			return m.openStats(), nil
+		}
 	case "r":
-		if m.timerRunning {
-			if m.timer == TIMER_1 {
-				m.timer = TIMER_2
-			}else if m.timer == TIMER_2 {
-				m.timer = TIMER_1
-			}
+		if m.timer.running {
+			next := (m.config.ProfileIndex(m.timer.profile.Name) + 1) % len(m.config.Profiles)
+			return m.afterChange(m.timer.switchProfile(m.config.Profiles[next]))
 		}
 	case "d":
-		if !m.timerRunning {
-			if m.timerDefault == TIMER_1 {
-				m.timerDefault = TIMER_2
-			}else if m.timerDefault == TIMER_2 {
-				m.timerDefault = TIMER_1
-			}
-		}
-	case "1":
-		return m.handleTimerToggle(TIMER_1), nil
-	case "2":
-		return m.handleTimerToggle(TIMER_2), nil
+		if !m.timer.running {
+			m.timerDefault = (m.timerDefault + 1) % len(m.config.Profiles)
+			m.config.DefaultProfile = m.config.Profiles[m.timerDefault].Name
+			config.SaveConfig(m.config)
+		}
+	case "p":
+		return m.handlePauseToggle(), nil
+	case "n":
+		return m.afterChange(m.timer.skip(1, time.Now()))
+	case "b":
+		return m.afterChange(m.timer.skip(-1, time.Now()))
+	case "+", "=":
+		m.timer.extend(30 * time.Second)
+		return m.afterChange(false)
+	case "m":
+		m.timer.extend(time.Minute)
+		return m.afterChange(false)
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		timer := int(msg.String()[0] - '1')
+		if timer < len(m.config.Profiles) {
+			return m.handleTimerToggle(timer)
+		}
 	case "enter", "":
-		return m.handleTimerToggle(m.timerDefault), nil
+		return m.handleTimerToggle(m.timerDefault)
+	}
+	return m, nil
+}
+
+// afterChange saves the session and refreshes the status bar after the timer
+// was changed by hand, ringing if that moved it to another phase
+func (m model) afterChange(phaseChanged bool) (tea.Model, tea.Cmd) {
+	m.timer.save()
+	m.files.writeTimerState(m.timer, m.texts)
+	if phaseChanged {
+		return m, m.dingCmd()
 	}
 	return m, nil
 }
 
-func (m model) handleTimerToggle(timer int) model {
-	if !m.timerRunning {
-		m.timerRunning = true
-		m.timerStart = time.Now()
-		m.timerElapsed = 0
-		m.lastPhase = phaseNotStarted
-		m.timer = timer
-	} else {
-		m.timerRunning = false
-		m.timerElapsed = 0
-		m.currentPhase = phaseNotStarted
-		m.lastPhase = phaseNotStarted
+func (m model) handleTimerToggle(timer int) (model, tea.Cmd) {
+	if m.timer.running {
+		m.timer.stop()
+		m.timer.save()
+		return m, nil
+	}
+	// The break may have been started from the command line since
+	m = m.refreshBreak()
+	now := time.Now()
+	var warnings []string
+	if until, reason := sessionLimit(m.config.Limits, now); !until.IsZero() {
+		m.limitUntil, m.limitReason = until, reason
+		if m.config.Limits.Enforce == "refuse" {
+			return m, nil
+		}
+		warnings = append(warnings, limitMessage(until, reason, now))
+	}
+	if m.onBreak != nil {
+		warnings = append(warnings, fmt.Sprintf("You're on day %d of your break", breakDay(m.onBreak.Start, now)))
 	}
+	if len(warnings) > 0 {
+		m.pendingStart = &pendingStart{profile: timer, warning: strings.Join(warnings, ". ")}
+		return m, nil
+	}
+	return m.startTimer(timer)
+}
+
+// startTimer starts the profile at the given index
+func (m model) startTimer(timer int) (model, tea.Cmd) {
+	var ding tea.Cmd
+	if m.timer.begin(m.profile(timer), time.Now()) {
+		ding = m.dingCmd()
+	}
+	m.timer.save()
+	return m, ding
+}
+
+func (m model) handlePauseToggle() model {
+	m.timer.togglePause(time.Now())
+	m.timer.save()
 	return m
-}
\ No newline at end of file
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 	"time"
+
+	"github.com/charmbracelet/lipgloss"
 	util "github.com/unquenchedservant/ChillClock/utilities"
 )
 
@@ -11,11 +14,26 @@ func (m model) View() string {
 		return "Loading..."
 	}
 
-	if m.mode == viewConfig {
-		return m.renderConfigView()
+	view := m.renderClockView()
+	switch m.mode {
+	case viewConfig:
+		view = m.renderConfigView()
+	case viewStats:
+		view = m.renderStatsView()
+	case viewLog:
+		view = m.renderLogView()
 	}
-
-	return m.renderClockView()
+	if time.Now().Before(m.flashUntil) {
+		// The renderer rewrites a line whenever it changes, so the
+		// notification sequence stays on its own until it's dropped after
+		// the first frames, see flashSentMsg
+		view = m.flashSequence + m.renderFlash()
+	}
+	if m.config.TerminalProgress && m.flashSequence == "" {
+		// The sequence takes no room, so it can ride along with the view
+		view = progressSequence(m.timer) + view
+	}
+	return view
 }
 
 func (m model) renderClockView() string {
@@ -28,6 +46,9 @@ func (m model) renderClockView() string {
 	var output strings.Builder
 
 	totalLines := 1 + len(clockLines) + 3
+	if m.onBreak != nil {
+		totalLines++
+	}
 	topPadding := (m.height - totalLines) / 2
 
 	for i := 0; i < topPadding; i++ {
@@ -35,7 +56,13 @@ func (m model) renderClockView() string {
 	}
 
 	output.WriteString(util.CenterText(util.GetYellowStyle().Render(dateStr), m.width))
-	output.WriteString("\n\n")
+	output.WriteString("\n")
+	if m.onBreak != nil {
+		breakStr := fmt.Sprintf("Day %d of break · longest streak %s", breakDay(m.onBreak.Start, now), formatDays(m.streak))
+		output.WriteString(util.CenterText(util.GetGreenStyle().Render(breakStr), m.width))
+		output.WriteString("\n")
+	}
+	output.WriteString("\n")
 
 	for _, line := range clockLines {
 		styledLine := util.GetGreenStyle().Render(line)
@@ -46,6 +73,29 @@ func (m model) renderClockView() string {
 	output.WriteString("\n")
 	timerText, timerStyle := m.getTimerDisplay()
 	output.WriteString(timerStyle.Render(timerText))
+	if m.notifyError != "" {
+		output.WriteString("\n")
+		output.WriteString(util.GetRedStyle().Width(m.width).Align(lipgloss.Center).Render("Notification failed: " + m.notifyError))
+	}
 
 	return output.String()
-}
\ No newline at end of file
+}
+
+// flashDuration is how long the screen flashes for a terminal notification
+const flashDuration = 750 * time.Millisecond
+
+// flashSequenceHold is how long the notification sequence stays in the view,
+// long enough for a frame or two to be drawn but not for a resize to send it
+// again
+const flashSequenceHold = 100 * time.Millisecond
+
+// renderFlash fills the screen with the notification in reverse video
+func (m model) renderFlash() string {
+	text := m.flash.Title + "\n" + m.flash.Body
+	return lipgloss.NewStyle().
+		Reverse(true).
+		Width(m.width).
+		Height(m.height).
+		Align(lipgloss.Center, lipgloss.Center).
+		Render(text)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Break is a tolerance break: a stretch of days without sessions that the
+// user has set out to keep
+type Break struct {
+	Start   time.Time `json:"start"`             // midnight at the start of the first day
+	End     time.Time `json:"end,omitempty"`     // when it ended, zero while it's going
+	Outcome string    `json:"outcome,omitempty"` // "completed" when it was ended, "broken" when a session was started
+}
+
+func getBreakFile() (string, error) {
+	stateDir, err := GetStatePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "break.json"), nil
+}
+
+func getBreakLogFile() (string, error) {
+	stateDir, err := GetStatePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "breaks.jsonl"), nil
+}
+
+// LoadBreak loads the break that's going on. It returns nil without an error
+// when there is none.
+func LoadBreak() (*Break, error) {
+	breakFile, err := getBreakFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(breakFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var b Break
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// StartBreak starts a break on the given day
+func StartBreak(start time.Time) error {
+	breakFile, err := getBreakFile()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(breakFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(Break{Start: start}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(breakFile, data, 0644)
+}
+
+// EndBreak ends the break that's going on, if there is one, and adds it to
+// the log of past breaks with its outcome
+func EndBreak(end time.Time, outcome string) error {
+	b, err := LoadBreak()
+	if err != nil || b == nil {
+		return err
+	}
+	b.End, b.Outcome = end, outcome
+
+	logFile, err := getBreakLogFile()
+	if err != nil {
+		return err
+	}
+	if err := appendLine(logFile, b); err != nil {
+		return err
+	}
+
+	breakFile, err := getBreakFile()
+	if err != nil {
+		return err
+	}
+	return os.Remove(breakFile)
+}
+
+// LoadBreaks loads the past breaks, oldest first
+func LoadBreaks() ([]Break, error) {
+	logFile, err := getBreakLogFile()
+	if err != nil {
+		return nil, err
+	}
+	return loadLines[Break](logFile)
+}
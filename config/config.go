@@ -2,17 +2,91 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Timer TimerConfig `json:"timer"`
+	Profiles       []Profile `json:"profiles"`
+	DefaultProfile string    `json:"default_profile"`
+	StateFile      string    `json:"state_file,omitempty"`  // status bar file, in the runtime dir when empty
+	TriggerDir     string    `json:"trigger_dir,omitempty"` // where the click files are touched, the runtime dir when empty
+	Sinks          []Sink    `json:"sinks,omitempty"`       // status bar outputs, a Waybar file when empty
+	Templates      Templates `json:"templates,omitempty"`
+	TmuxRefresh    bool      `json:"tmux_refresh,omitempty"` // redraw tmux status lines when the timer changes
+	// Notification backends to try in order until one works: desktop, dbus,
+	// notify-send, osascript, toast, osc9, osc777 and webhook
+	NotificationBackends []string `json:"notification_backends,omitempty"`
+	NotificationWebhook  string   `json:"notification_webhook,omitempty"` // URL the webhook backend posts to
+	// Show the timer in the terminal title and as tab or taskbar progress
+	TerminalTitle    bool `json:"terminal_title,omitempty"`
+	TerminalProgress bool `json:"terminal_progress,omitempty"`
+	// Limits on how often sessions can be started
+	Limits Limits `json:"limits,omitempty"`
+	// When to ask for the amount, strain and rating of a session: "start",
+	// "finish" or never when empty
+	LogPrompt string `json:"log_prompt,omitempty"`
+	// Web pages allowed to use the HTTP API, e.g. "http://localhost:3000"
+	HTTPOrigins []string `json:"http_origins,omitempty"`
 }
 
-// TimerConfig holds timer-specific configuration
-type TimerConfig struct {
+// Limits restrict how often sessions can be started. Zero values mean no
+// limit.
+type Limits struct {
+	MaxPerDay     int    `json:"max_per_day,omitempty"`     // sessions started per day
+	MinGapMinutes int    `json:"min_gap_minutes,omitempty"` // from the end of one session to the start of the next
+	Enforce       string `json:"enforce,omitempty"`         // "warn" to ask before starting anyway (the default) or "refuse"
+}
+
+// Templates are text/template strings that replace the built-in text. Empty
+// ones keep the built-in text.
+type Templates struct {
+	StatusBar         string `json:"status_bar,omitempty"`
+	TimerLine         string `json:"timer_line,omitempty"`
+	NotificationTitle string `json:"notification_title,omitempty"`
+	NotificationBody  string `json:"notification_body,omitempty"`
+}
+
+// Sink is a status bar output: the format a bar reads and where it's written
+type Sink struct {
+	Format string `json:"format"`         // waybar, i3bar, i3blocks or polybar
+	Path   string `json:"path,omitempty"` // file to write, "-" for stdout or empty for the default
+}
+
+// Profile is a named timer made up of an ordered list of phases
+type Profile struct {
+	Name   string  `json:"name"`
+	Phases []Phase `json:"phases"`
+}
+
+// Phase is a single step of a timer: how long it runs and the temperature to set
+type Phase struct {
+	DurationMinutes int    `json:"duration_minutes"`
+	Temp            int    `json:"temp"`
+	Name            string `json:"name,omitempty"` // optional, e.g. "Warm up"
+}
+
+// PhaseName returns the name of the phase at index i, or "Phase N" when it
+// has none
+func PhaseName(phases []Phase, i int) string {
+	if i >= 0 && i < len(phases) && phases[i].Name != "" {
+		return phases[i].Name
+	}
+	return fmt.Sprintf("Phase %d", i+1)
+}
+
+// listTimerConfig is the two-timer layout with a list of phases per timer. It
+// is only read to migrate older config files.
+type listTimerConfig struct {
+	Phases_Timer1 []Phase `json:"timer1_phases"`
+	Phases_Timer2 []Phase `json:"timer2_phases"`
+}
+
+// legacyTimerConfig is the fixed three-phase layout used before timers held a
+// list of phases. It is only read to migrate older config files.
+type legacyTimerConfig struct {
 	Phase1Duration_Timer1 int `json:"phase1_timer1_duration_minutes"`
 	Phase2Duration_Timer1 int `json:"phase2_timer1_duration_minutes"`
 	Phase3Duration_Timer1 int `json:"phase3_timer1_duration_minutes"`
@@ -27,23 +101,77 @@ type TimerConfig struct {
 	Phase3Temp_Timer2     int `json:"phase3_timer2_temp"`
 }
 
+func (l legacyTimerConfig) isSet() bool {
+	return l != legacyTimerConfig{}
+}
+
+func (l legacyTimerConfig) toProfiles() []Profile {
+	return []Profile{
+		{Name: "Timer 1", Phases: []Phase{
+			{DurationMinutes: l.Phase1Duration_Timer1, Temp: l.Phase1Temp_Timer1},
+			{DurationMinutes: l.Phase2Duration_Timer1, Temp: l.Phase2Temp_Timer1},
+			{DurationMinutes: l.Phase3Duration_Timer1, Temp: l.Phase3Temp_Timer1},
+		}},
+		{Name: "Timer 2", Phases: []Phase{
+			{DurationMinutes: l.Phase1Duration_Timer2, Temp: l.Phase1Temp_Timer2},
+			{DurationMinutes: l.Phase2Duration_Timer2, Temp: l.Phase2Temp_Timer2},
+			{DurationMinutes: l.Phase3Duration_Timer2, Temp: l.Phase3Temp_Timer2},
+		}},
+	}
+}
+
+func (l listTimerConfig) toProfiles() []Profile {
+	return []Profile{
+		{Name: "Timer 1", Phases: l.Phases_Timer1},
+		{Name: "Timer 2", Phases: l.Phases_Timer2},
+	}
+}
+
+// NotificationOrder returns the notification backends to try in order,
+// falling back to the terminal when there are no desktop notifications
+func (c Config) NotificationOrder() []string {
+	if len(c.NotificationBackends) == 0 {
+		return []string{"desktop", "osc9"}
+	}
+	return c.NotificationBackends
+}
+
+// ProfileIndex returns the index of the profile with the given name, or -1 if
+// there is none
+func (c Config) ProfileIndex(name string) int {
+	for i, p := range c.Profiles {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// TotalMinutes returns the combined duration of a list of phases
+func TotalMinutes(phases []Phase) int {
+	total := 0
+	for _, p := range phases {
+		total += p.DurationMinutes
+	}
+	return total
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() Config {
 	return Config{
-		Timer: TimerConfig{
-			Phase1Duration_Timer1: 4,
-			Phase2Duration_Timer1: 4,
-			Phase3Duration_Timer1: 2,
-			Phase1Temp_Timer1:     350,
-			Phase2Temp_Timer1:     375,
-			Phase3Temp_Timer1:     400,
-			Phase1Duration_Timer2: 4,
-			Phase2Duration_Timer2: 6,
-			Phase3Duration_Timer2: 5,
-			Phase1Temp_Timer2:     350,
-			Phase2Temp_Timer2:     375,
-			Phase3Temp_Timer2:     400,
+		Profiles: []Profile{
+			{Name: "Timer 1", Phases: []Phase{
+				{DurationMinutes: 4, Temp: 350},
+				{DurationMinutes: 4, Temp: 375},
+				{DurationMinutes: 2, Temp: 400},
+			}},
+			{Name: "Timer 2", Phases: []Phase{
+				{DurationMinutes: 4, Temp: 350},
+				{DurationMinutes: 6, Temp: 375},
+				{DurationMinutes: 5, Temp: 400},
+			}},
 		},
+		DefaultProfile: "Timer 1",
 	}
 }
 
@@ -99,9 +227,43 @@ func LoadConfig() (Config, error) {
 		return Config{}, err
 	}
 
+	if migrateConfig(&cfg, data) {
+		if err := SaveConfig(cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
 	return cfg, nil
 }
 
+// migrateConfig fills in anything an older config file is missing and reports
+// whether cfg was changed
+func migrateConfig(cfg *Config, data []byte) bool {
+	if len(cfg.Profiles) > 0 {
+		return false
+	}
+
+	var old struct {
+		Timer struct {
+			listTimerConfig
+			legacyTimerConfig
+		} `json:"timer"`
+	}
+	json.Unmarshal(data, &old)
+	switch {
+	case len(old.Timer.Phases_Timer1) > 0 || len(old.Timer.Phases_Timer2) > 0:
+		cfg.Profiles = old.Timer.listTimerConfig.toProfiles()
+	case old.Timer.legacyTimerConfig.isSet():
+		cfg.Profiles = old.Timer.legacyTimerConfig.toProfiles()
+	default:
+		cfg.Profiles = DefaultConfig().Profiles
+	}
+	if cfg.ProfileIndex(cfg.DefaultProfile) < 0 {
+		cfg.DefaultProfile = cfg.Profiles[0].Name
+	}
+	return true
+}
+
 // SaveConfig saves the configuration to disk
 func SaveConfig(cfg Config) error {
 	configDir, err := GetConfigPath()
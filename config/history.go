@@ -0,0 +1,123 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionRecord is a finished timer session as kept in the history
+type SessionRecord struct {
+	Profile   string        `json:"profile"`
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Phases    []PhaseRecord `json:"phases"`    // the phases the session got to, in order
+	Completed bool          `json:"completed"` // false when it was stopped early
+	Log       *SessionLog   `json:"log,omitempty"`
+}
+
+// SessionLog is what the user noted down about a session
+type SessionLog struct {
+	Grams  float64 `json:"grams,omitempty"` // amount loaded
+	Strain string  `json:"strain,omitempty"`
+	Rating int     `json:"rating,omitempty"` // 1 to 5
+	Notes  string  `json:"notes,omitempty"`
+}
+
+// PhaseRecord is how long a session actually spent in one of its phases
+type PhaseRecord struct {
+	Name     string        `json:"name,omitempty"`
+	Temp     int           `json:"temp"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Duration returns the time the session spent in its phases, leaving out
+// time spent paused
+func (r SessionRecord) Duration() time.Duration {
+	var total time.Duration
+	for _, p := range r.Phases {
+		total += p.Duration
+	}
+	return total
+}
+
+// GetHistoryFile returns the path of the session history, a JSON record per
+// line in the state dir
+func GetHistoryFile() (string, error) {
+	stateDir, err := GetStatePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "history.jsonl"), nil
+}
+
+// AppendHistory adds a finished session to the end of the history
+func AppendHistory(record SessionRecord) error {
+	historyFile, err := GetHistoryFile()
+	if err != nil {
+		return err
+	}
+	return appendLine(historyFile, record)
+}
+
+// appendLine adds v as a line of JSON to the end of file
+func appendLine(file string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// A single write of the whole line keeps the clock and the daemon from
+	// interleaving their records
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadHistory loads every session in the history, oldest first. It returns
+// nil without an error when there is no history yet.
+func LoadHistory() ([]SessionRecord, error) {
+	historyFile, err := GetHistoryFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return loadLines[SessionRecord](historyFile)
+}
+
+// loadLines reads a file with a JSON value per line. It returns nil without
+// an error when the file doesn't exist. Lines that can't be read, like one
+// cut short by a crash, are skipped.
+func loadLines[T any](file string) ([]T, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var values []T
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var value T
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values, scanner.Err()
+}
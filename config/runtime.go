@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StateFileName is the name of the status bar file
+const StateFileName = "dhv_timer.txt"
+
+// GetRuntimeDir returns the directory for files that only matter while
+// cclock runs: the control socket, the status bar file and the click files.
+// It is $XDG_RUNTIME_DIR/chillclock, or the run directory in the state dir
+// when $XDG_RUNTIME_DIR isn't set. There's no fallback to the shared temp dir,
+// where another user of the machine could make the directory ahead of time.
+func GetRuntimeDir() (string, error) {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "chillclock"), nil
+	}
+	stateDir, err := GetStatePath()
+	if err != nil {
+		return "", fmt.Errorf("no runtime dir, set $XDG_RUNTIME_DIR: %w", err)
+	}
+	return filepath.Join(stateDir, "run"), nil
+}
+
+// ExpandPath expands a leading ~ to the home directory
+func ExpandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
+
+// StateFilePath returns where the status bar file is written
+func (c Config) StateFilePath() (string, error) {
+	if c.StateFile != "" {
+		return ExpandPath(c.StateFile), nil
+	}
+	runtimeDir, err := GetRuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(runtimeDir, StateFileName), nil
+}
+
+// TriggerDirPath returns the directory the click files are touched in
+func (c Config) TriggerDirPath() (string, error) {
+	if c.TriggerDir != "" {
+		return ExpandPath(c.TriggerDir), nil
+	}
+	return GetRuntimeDir()
+}
+
+// StatusSinks returns the configured status bar sinks, or a single Waybar
+// sink when none are configured
+func (c Config) StatusSinks() []Sink {
+	if len(c.Sinks) == 0 {
+		return []Sink{{Format: "waybar"}}
+	}
+	return c.Sinks
+}
+
+// SinkPath returns where a sink is written. Waybar sinks default to the state
+// file and the other formats to a file named after them next to it.
+func (c Config) SinkPath(sink Sink) (string, error) {
+	if sink.Path != "" {
+		return ExpandPath(sink.Path), nil
+	}
+	stateFile, err := c.StateFilePath()
+	if err != nil || sink.Format == "waybar" {
+		return stateFile, err
+	}
+	return filepath.Join(filepath.Dir(stateFile), fmt.Sprintf("dhv_timer_%s.txt", sink.Format)), nil
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionState is a running timer session as saved to disk, so it can be
+// picked up again after cclock is closed or crashes
+type SessionState struct {
+	Profile        string          `json:"profile"`
+	Start          time.Time       `json:"start"`
+	Began          time.Time       `json:"began,omitempty"` // when it was started, Start is moved on by pauses
+	PhaseDurations []time.Duration `json:"phase_durations"`
+	Paused         bool            `json:"paused"`
+	PausedElapsed  time.Duration   `json:"paused_elapsed"`
+	PhaseTimes     []time.Duration `json:"phase_times,omitempty"`   // time spent in each phase until the last phase change
+	PhaseEntered   time.Duration   `json:"phase_entered,omitempty"` // elapsed time when the current phase was entered
+	Log            *SessionLog     `json:"log,omitempty"`           // noted down when it started
+}
+
+// Elapsed returns how long the session has been running at the given time
+func (s SessionState) Elapsed(now time.Time) time.Duration {
+	if s.Paused {
+		return s.PausedElapsed
+	}
+	return now.Sub(s.Start)
+}
+
+// GetStatePath returns the path to the state directory, following
+// $XDG_STATE_HOME and falling back to ~/.local/state
+func GetStatePath() (string, error) {
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "ChillClock"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "state", "ChillClock"), nil
+}
+
+func getSessionFile() (string, error) {
+	stateDir, err := GetStatePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "session.json"), nil
+}
+
+// LoadSession loads the saved session. It returns nil without an error when
+// no session is saved.
+func LoadSession() (*SessionState, error) {
+	sessionFile, err := getSessionFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(sessionFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state SessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveSession saves the running session to disk
+func SaveSession(state SessionState) error {
+	sessionFile, err := getSessionFile()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sessionFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves half a session
+	tmpFile := sessionFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, sessionFile)
+}
+
+// ClearSession removes the saved session, if there is one
+func ClearSession() error {
+	sessionFile, err := getSessionFile()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(sessionFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/unquenchedservant/ChillClock/config"
+)
+
+// ErrNotRunning is returned by Send when there is no cclock instance to talk to
+var ErrNotRunning = errors.New("no running cclock instance found")
+
+// ErrAlreadyRunning is returned by Listen when another instance already owns
+// the control socket
+var ErrAlreadyRunning = errors.New("cclock is already running")
+
+// Request is a command sent to a running cclock. The commands are status,
+// start, stop, toggle, pause, resume, skip, extend and subscribe.
+type Request struct {
+	Command string `json:"command"`
+	Profile string `json:"profile,omitempty"` // start and toggle: profile name or number
+	Steps   int    `json:"steps,omitempty"`   // skip: phases to move, negative to go back
+	Seconds int    `json:"seconds,omitempty"` // extend: time to add to the current phase
+}
+
+// Response is the reply to a Request
+type Response struct {
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status describes the timer of a running cclock
+type Status struct {
+	State            string `json:"state"` // idle, running, paused or completed
+	Profile          string `json:"profile,omitempty"`
+	Phase            int    `json:"phase"`
+	PhaseCount       int    `json:"phase_count"`
+	Temp             int    `json:"temp"`
+	ElapsedSeconds   int    `json:"elapsed_seconds"`
+	RemainingSeconds int    `json:"remaining_seconds"`
+	TotalSeconds     int    `json:"total_seconds"`
+}
+
+// Event is sent to subscribers whenever the timer changes. The types are
+// started, phase, paused, resumed, extended, stopped and completed.
+type Event struct {
+	Type   string `json:"event"`
+	Status Status `json:"status"`
+}
+
+// Handler carries out a request and returns the reply
+type Handler func(Request) Response
+
+// GetSocketPath returns the path of the control socket in the runtime dir
+func GetSocketPath() (string, error) {
+	runtimeDir, err := config.GetRuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(runtimeDir, "cclock.sock"), nil
+}
+
+// Server accepts requests on the control socket
+type Server struct {
+	listener net.Listener
+	handler  Handler
+	path     string
+	closed   chan struct{}
+	once     sync.Once
+
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// Listen opens the control socket. A socket left behind by an instance that
+// didn't exit cleanly is replaced, but one that still answers is not.
+func Listen(handler Handler) (*Server, error) {
+	path, err := GetSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, ErrAlreadyRunning
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		listener:    listener,
+		handler:     handler,
+		path:        path,
+		closed:      make(chan struct{}),
+		subscribers: make(map[chan Event]struct{}),
+	}, nil
+}
+
+// Serve accepts connections until the server is closed
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closed:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers each request line on a connection with a response line
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Response{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else if req.Command == "subscribe" {
+			s.serveSubscriber(conn, encoder)
+			return
+		} else {
+			resp = s.handler(req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// serveSubscriber replies with the current status and then streams events to
+// the connection until the client goes away or the server is closed
+func (s *Server) serveSubscriber(conn net.Conn, encoder *json.Encoder) {
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+
+	if err := encoder.Encode(s.handler(Request{Command: "status"})); err != nil {
+		return
+	}
+
+	// Subscribers don't send anything else, so a finished read means they
+	// hung up
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// subscribe registers a new subscriber and returns the channel its events
+// arrive on. The channel is closed if the subscriber falls behind.
+func (s *Server) subscribe() chan Event {
+	events := make(chan Event, 16)
+	s.mu.Lock()
+	s.subscribers[events] = struct{}{}
+	s.mu.Unlock()
+	return events
+}
+
+func (s *Server) unsubscribe(events chan Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[events]; ok {
+		delete(s.subscribers, events)
+		close(events)
+	}
+}
+
+// Publish sends an event to every subscriber. A subscriber that can't keep up
+// is dropped rather than holding up the timer.
+func (s *Server) Publish(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for events := range s.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(s.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Close stops the server and removes the socket
+func (s *Server) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.closed)
+		err = s.listener.Close()
+		os.Remove(s.path)
+	})
+	return err
+}
+
+// Send sends a request to the running instance and waits for the reply
+func Send(req Request) (Response, error) {
+	path, err := GetSocketPath()
+	if err != nil {
+		return Response{}, err
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return Response{}, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, err
+	}
+
+	var resp Response
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return Response{}, err
+		}
+		return Response{}, errors.New("no reply from cclock")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		return Response{}, err
+	}
+	return resp, nil
+}
+
+// Subscribe streams the running instance's events to handle until handle
+// returns an error or the connection closes. The status at the time of
+// subscribing is passed to handle first as an event of type "status".
+func Subscribe(handle func(Event) error) error {
+	path, err := GetSocketPath()
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return ErrNotRunning
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(Request{Command: "subscribe"}); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		return errors.New("no reply from cclock")
+	}
+	var resp Response
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+	if resp.Status != nil {
+		if err := handle(Event{Type: "status", Status: *resp.Status}); err != nil {
+			return err
+		}
+	}
+
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return err
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
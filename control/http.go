@@ -0,0 +1,194 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// httpCommands are the commands that can be POSTed to the HTTP API
+var httpCommands = map[string]bool{
+	"start": true, "stop": true, "toggle": true, "pause": true,
+	"resume": true, "skip": true, "extend": true,
+}
+
+// HTTPHandler serves the control API over HTTP:
+//
+//	GET  /status    the current status as JSON
+//	GET  /events    a Server-Sent Events stream of timer events
+//	POST /COMMAND   start, stop, toggle, pause, resume, skip or extend
+//
+// Commands need a JSON Content-Type, which a web page can't send to another
+// site without the browser asking first, and take their arguments (profile,
+// steps, seconds) from the JSON body or the query string. They reply with a
+// Response.
+//
+// Browsers may only use the API from the origins listed, e.g.
+// "http://localhost:3000". Requests from any other page are refused, as are
+// requests for a Host other than a loopback address or localhost, which keeps
+// DNS rebinding out.
+func (s *Server) HTTPHandler(origins []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleHTTPStatus)
+	mux.HandleFunc("GET /events", s.handleHTTPEvents)
+	mux.HandleFunc("POST /{command}", s.handleHTTPCommand)
+	mux.HandleFunc("OPTIONS /{command}", handleHTTPPreflight)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !localHost(r.Host) {
+			writeJSON(w, http.StatusForbidden, Response{Error: fmt.Sprintf("host %q not allowed", r.Host)})
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !slices.Contains(origins, origin) {
+				writeJSON(w, http.StatusForbidden, Response{Error: fmt.Sprintf("origin %q not allowed", origin)})
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Add("Vary", "Origin")
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// ListenHTTP opens addr for the HTTP API. There is no authentication, so
+// only loopback addresses are allowed.
+func ListenHTTP(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !localHost(host) {
+		return nil, fmt.Errorf("%s isn't a loopback address, use e.g. 127.0.0.1:8765", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// localHost reports whether host, with or without a port, is a loopback
+// address or localhost rather than a name anyone could point at this machine
+func localHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	return strings.EqualFold(host, "localhost")
+}
+
+// handleHTTPPreflight lets an allowed origin send commands with a JSON body
+func handleHTTPPreflight(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", "POST")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleHTTPStatus(w http.ResponseWriter, r *http.Request) {
+	resp := s.handler(Request{Command: "status"})
+	if resp.Status == nil {
+		writeJSON(w, http.StatusInternalServerError, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp.Status)
+}
+
+func (s *Server) handleHTTPCommand(w http.ResponseWriter, r *http.Request) {
+	req := Request{Command: r.PathValue("command")}
+	if !httpCommands[req.Command] {
+		writeJSON(w, http.StatusNotFound, Response{Error: fmt.Sprintf("unknown command %q", req.Command)})
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeJSON(w, http.StatusUnsupportedMediaType, Response{Error: "commands need Content-Type: application/json"})
+		return
+	}
+	// The body may be left empty when the arguments are in the query string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	req.Command = r.PathValue("command")
+	query := r.URL.Query()
+	if profile := query.Get("profile"); profile != "" {
+		req.Profile = profile
+	}
+	for name, field := range map[string]*int{"steps": &req.Steps, "seconds": &req.Seconds} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, Response{Error: fmt.Sprintf("invalid %s %q", name, value)})
+				return
+			}
+			*field = n
+		}
+	}
+
+	resp := s.handler(req)
+	if !resp.OK {
+		writeJSON(w, http.StatusConflict, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleHTTPEvents streams events in the Server-Sent Events format, starting
+// with the current status
+func (s *Server) handleHTTPEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if resp := s.handler(Request{Command: "status"}); resp.Status != nil {
+		if err := writeSSE(w, Event{Type: "status", Status: *resp.Status}); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
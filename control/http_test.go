@@ -0,0 +1,111 @@
+package control
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testHandler serves the HTTP API on top of a handler that records the
+// commands it was given
+func testHandler(origins []string) (http.Handler, *[]Request) {
+	var requests []Request
+	server := &Server{
+		handler: func(req Request) Response {
+			requests = append(requests, req)
+			return Response{OK: true, Status: &Status{State: "idle"}}
+		},
+		closed:      make(chan struct{}),
+		subscribers: make(map[chan Event]struct{}),
+	}
+	return server.HTTPHandler(origins), &requests
+}
+
+func TestHTTPCommands(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		origin      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{name: "json", contentType: "application/json", body: `{"profile": "2"}`, code: http.StatusOK},
+		{name: "empty json body", contentType: "application/json", code: http.StatusOK},
+		{name: "form from a web page", contentType: "application/x-www-form-urlencoded", code: http.StatusUnsupportedMediaType},
+		{name: "no content type", code: http.StatusUnsupportedMediaType},
+		{name: "foreign origin", origin: "https://example.com", contentType: "application/json", code: http.StatusForbidden},
+		{name: "allowed origin", origin: "http://localhost:3000", contentType: "application/json", code: http.StatusOK},
+		{name: "rebound host", host: "attacker.example:8765", contentType: "application/json", code: http.StatusForbidden},
+		{name: "lan host", host: "192.168.1.20:8765", contentType: "application/json", code: http.StatusForbidden},
+		{name: "localhost", host: "localhost:8765", contentType: "application/json", code: http.StatusOK},
+		{name: "ipv6 loopback", host: "[::1]:8765", contentType: "application/json", code: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, requests := testHandler([]string{"http://localhost:3000"})
+			req := httptest.NewRequest("POST", "http://127.0.0.1:8765/start", strings.NewReader(tt.body))
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+			if ran := len(*requests) > 0; ran != (tt.code == http.StatusOK) {
+				t.Errorf("command ran = %v with status %d", ran, rec.Code)
+			}
+			if tt.body != "" && tt.code == http.StatusOK && (*requests)[0].Profile != "2" {
+				t.Errorf("profile = %q, want 2", (*requests)[0].Profile)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusCORS(t *testing.T) {
+	handler, _ := testHandler([]string{"http://localhost:3000"})
+
+	req := httptest.NewRequest("GET", "http://127.0.0.1:8765/status", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("plain status: %d, allowed origin %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	req.Header.Set("Origin", "http://localhost:3000")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Errorf("allowed origin = %q, want http://localhost:3000", got)
+	}
+
+	req.Header.Set("Origin", "https://example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status from a foreign origin = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestListenHTTPLoopbackOnly(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", ":0", "192.0.2.1:0", "example.com:0"} {
+		if listener, err := ListenHTTP(addr); err == nil {
+			listener.Close()
+			t.Errorf("ListenHTTP(%q) didn't refuse a non-loopback address", addr)
+		}
+	}
+	listener, err := ListenHTTP("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenHTTP on loopback: %v", err)
+	}
+	listener.Close()
+}
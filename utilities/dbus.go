@@ -0,0 +1,114 @@
+package utilities
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsName  = "org.freedesktop.Notifications"
+	notificationsPath  = "/org/freedesktop/Notifications"
+	notificationsIface = "org.freedesktop.Notifications"
+)
+
+// DBusNotifier sends notifications to org.freedesktop.Notifications on the
+// session bus. Each notification replaces the previous one, so a session
+// shows a single popup that moves from phase to phase.
+type DBusNotifier struct {
+	onAction func(key string)
+
+	mu   sync.Mutex
+	conn *dbus.Conn // connected on the first notification
+	id   uint32     // ID of the last notification, 0 before the first
+}
+
+// NewDBusNotifier returns a notifier that connects to the session bus when
+// it's first used. onAction is called with the key of every action button the
+// user presses on its notifications.
+func NewDBusNotifier(onAction func(key string)) *DBusNotifier {
+	return &DBusNotifier{onAction: onAction}
+}
+
+// connect opens the session bus connection and starts listening for action
+// buttons. It's called with mu held.
+func (n *DBusNotifier) connect() error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(notificationsPath),
+		dbus.WithMatchInterface(notificationsIface),
+		dbus.WithMatchMember("ActionInvoked"),
+	); err != nil {
+		conn.Close()
+		return err
+	}
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+	go n.watchActions(signals)
+	n.conn = conn
+	return nil
+}
+
+// watchActions passes the actions invoked on our notification to onAction
+// until the connection closes
+func (n *DBusNotifier) watchActions(signals chan *dbus.Signal) {
+	for signal := range signals {
+		if signal.Name != notificationsIface+".ActionInvoked" || len(signal.Body) != 2 {
+			continue
+		}
+		id, _ := signal.Body[0].(uint32)
+		key, _ := signal.Body[1].(string)
+		n.mu.Lock()
+		ours := id != 0 && id == n.id
+		n.mu.Unlock()
+		if ours && key != "default" && n.onAction != nil {
+			n.onAction(key)
+		}
+	}
+}
+
+// Notify shows the notification in place of the last one sent
+func (n *DBusNotifier) Notify(notification Notification) error {
+	flat := make([]string, 0, 2*len(notification.Actions))
+	for _, action := range notification.Actions {
+		flat = append(flat, action.Key, action.Label)
+	}
+	hints := map[string]dbus.Variant{
+		"desktop-entry": dbus.MakeVariant("cclock"),
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connect(); err != nil {
+			return fmt.Errorf("dbus: %w", err)
+		}
+	}
+	call := n.conn.Object(notificationsName, notificationsPath).Call(
+		notificationsIface+".Notify", 0,
+		"ChillClock", n.id, "", notification.Title, notification.Body, flat, hints, int32(5000))
+	if call.Err != nil {
+		// Connect again next time, the bus or the daemon may have restarted
+		n.conn.Close()
+		n.conn = nil
+		return fmt.Errorf("dbus: %w", call.Err)
+	}
+	return call.Store(&n.id)
+}
+
+// Close disconnects from the session bus
+func (n *DBusNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	return err
+}
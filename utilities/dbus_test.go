@@ -0,0 +1,115 @@
+package utilities
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeNotifications is a notification daemon that hands out IDs and keeps the
+// replaces_id of every call
+type fakeNotifications struct {
+	mu       sync.Mutex
+	replaces []uint32
+	next     uint32
+}
+
+func (f *fakeNotifications) Notify(app string, replacesID uint32, icon, summary, body string, actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replaces = append(f.replaces, replacesID)
+	if replacesID != 0 {
+		return replacesID, nil
+	}
+	f.next++
+	return f.next, nil
+}
+
+// privateBus starts a dbus-daemon of its own for the test and points the
+// session bus at it
+func privateBus(t *testing.T) *dbus.Conn {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon isn't installed")
+	}
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("dbus-daemon didn't start: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the bus address: %v", err)
+	}
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestDBusNotifierReplacesAndDispatchesActions(t *testing.T) {
+	server := privateBus(t)
+	daemon := &fakeNotifications{}
+	if err := server.Export(daemon, notificationsPath, notificationsIface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := server.RequestName(notificationsName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("taking the notifications name: %v %v", reply, err)
+	}
+
+	actions := make(chan string, 4)
+	notifier := NewDBusNotifier(func(key string) { actions <- key })
+	defer notifier.Close()
+
+	for _, title := range []string{"Phase 1", "Phase 2"} {
+		if err := notifier.Notify(Notification{Title: title, Actions: []NotificationAction{{Key: "next", Label: "Next phase"}}}); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+	daemon.mu.Lock()
+	replaces := append([]uint32(nil), daemon.replaces...)
+	daemon.mu.Unlock()
+	if len(replaces) != 2 || replaces[0] != 0 || replaces[1] != 1 {
+		t.Fatalf("replaces_id of the calls = %v, want [0 1]", replaces)
+	}
+
+	// Only the buttons of our own notification count, and not a click on
+	// its body
+	for _, signal := range []struct {
+		id  uint32
+		key string
+	}{{7, "stop"}, {1, "default"}, {1, "next"}} {
+		if err := server.Emit(notificationsPath, notificationsIface+".ActionInvoked", signal.id, signal.key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case key := <-actions:
+		if key != "next" {
+			t.Errorf("action %q dispatched, want next", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the action wasn't dispatched")
+	}
+	select {
+	case key := <-actions:
+		t.Errorf("extra action %q dispatched", key)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -1,58 +1,234 @@
 package utilities
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os/exec"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
+// TimerPhase is the phase a timer is in. Phases are numbered from 1; the
+// constants below cover the states outside of a running phase.
 type TimerPhase int
 
 const (
-	phaseNotStarted TimerPhase = iota
-	phase1
-	phase2
-	phase3
-	phaseCompleted
+	PhaseNotStarted TimerPhase = 0
+	PhaseCompleted  TimerPhase = -1
 )
 
-func SendNotification(phase TimerPhase, temp int) {
-	var title, body string
-
-	switch phase {
-	case phase1:
-		title = "Phase 1"
-	case phase2:
-		title = "Phase 2"
-	case phase3:
-		title = "Phase 3"
-	case phaseCompleted:
-		title = "Timer Complete"
-		body = "All phases finished!"
-	default:
-		return
+// Notification is a message for the user
+type Notification struct {
+	Title   string
+	Body    string
+	Actions []NotificationAction // buttons, shown by notifiers that support them
+}
+
+// NotificationAction is a button on a notification. Key is passed to the
+// action handler when the button is pressed.
+type NotificationAction struct {
+	Key   string
+	Label string
+}
+
+// Notifier shows notifications one way. Notify fails when the notification
+// couldn't be shown, e.g. because there is no desktop over SSH.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// NotifierChain tries its notifiers in order until one of them works
+type NotifierChain []Notifier
+
+// Notify sends the notification through the first notifier that works
+func (c NotifierChain) Notify(n Notification) error {
+	_, err := c.Send(n)
+	return err
+}
+
+// Send sends the notification through the first notifier that works and
+// returns it. When none work, the error lists why each of them failed.
+func (c NotifierChain) Send(n Notification) (Notifier, error) {
+	if len(c) == 0 {
+		return nil, errors.New("no notifiers configured")
 	}
+	var errs []error
+	for _, notifier := range c {
+		err := notifier.Notify(n)
+		if err == nil {
+			return notifier, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// NotifySendNotifier shows notifications with notify-send
+type NotifySendNotifier struct{}
 
-	if phase != phaseCompleted {
-		body = fmt.Sprintf("%d°", temp)
+func (NotifySendNotifier) Notify(n Notification) error {
+	// -- so a title starting with - isn't taken for an option
+	return runNotifier("notify-send", "-u", "normal", "-t", "5000", "--", n.Title, n.Body)
+}
+
+// OSAScriptNotifier shows notifications on macOS with osascript
+type OSAScriptNotifier struct{}
+
+func (OSAScriptNotifier) Notify(n Notification) error {
+	script := fmt.Sprintf(`display notification "%s" with title "%s"`, appleScriptEscape(n.Body), appleScriptEscape(n.Title))
+	return runNotifier("osascript", "-e", script)
+}
+
+// ToastNotifier shows Windows toast notifications through PowerShell
+type ToastNotifier struct{}
+
+func (ToastNotifier) Notify(n Notification) error {
+	script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null; $Template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02); $RawXml = [xml] $Template.GetXml(); ($RawXml.toast.visual.binding.text|where {$_.id -eq "1"}).AppendChild($RawXml.CreateTextNode(%s)) > $null; ($RawXml.toast.visual.binding.text|where {$_.id -eq "2"}).AppendChild($RawXml.CreateTextNode(%s)) > $null; $SerializedXml = New-Object Windows.Data.Xml.Dom.XmlDocument; $SerializedXml.LoadXml($RawXml.OuterXml); $Toast = [Windows.UI.Notifications.ToastNotification]::new($SerializedXml); $Toast.Tag = "ChillClock"; $Toast.Group = "ChillClock"; $Notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("ChillClock"); $Notifier.Show($Toast);`, powerShellQuote(n.Title), powerShellQuote(n.Body))
+	return runNotifier("powershell", "-Command", script)
+}
+
+// runNotifier runs a notification command, putting what it printed in the
+// error when it fails
+func runNotifier(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
 	}
+	return nil
+}
 
+// DesktopNotifiers returns the desktop notifiers for this platform in the
+// order to try them. dbus is used on Linux before notify-send.
+func DesktopNotifiers(dbus *DBusNotifier) NotifierChain {
 	switch runtime.GOOS {
 	case "linux":
-		// Use notify-send for desktop notifications
-		exec.Command("notify-send", "-u", "normal", "-t", "5000", title, body).Run()
+		return NotifierChain{dbus, NotifySendNotifier{}}
 	case "darwin":
-		// macOS - use osascript to display notification
-		script := fmt.Sprintf(`display notification "%s" with title "%s"`, body, title)
-		exec.Command("osascript", "-e", script).Run()
+		return NotifierChain{OSAScriptNotifier{}}
 	case "windows":
-		// Windows - use PowerShell to show toast notification
-		script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null; $Template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02); $RawXml = [xml] $Template.GetXml(); ($RawXml.toast.visual.binding.text|where {$_.id -eq "1"}).AppendChild($RawXml.CreateTextNode("%s")) > $null; ($RawXml.toast.visual.binding.text|where {$_.id -eq "2"}).AppendChild($RawXml.CreateTextNode("%s")) > $null; $SerializedXml = New-Object Windows.Data.Xml.Dom.XmlDocument; $SerializedXml.LoadXml($RawXml.OuterXml); $Toast = [Windows.UI.Notifications.ToastNotification]::new($SerializedXml); $Toast.Tag = "ChillClock"; $Toast.Group = "ChillClock"; $Notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("ChillClock"); $Notifier.Show($Toast);`, title, body)
-		exec.Command("powershell", "-Command", script).Run()
+		return NotifierChain{ToastNotifier{}}
 	}
+	return nil
+}
+
+// TerminalNotifier asks the terminal to raise notifications with an escape
+// sequence. OSC 9 is understood by iTerm2, kitty, WezTerm and Windows
+// Terminal, OSC 777 by foot, urxvt, WezTerm and VTE based terminals.
+type TerminalNotifier struct {
+	W   io.Writer
+	OSC int // 9 or 777
+}
+
+func (t TerminalNotifier) Notify(n Notification) error {
+	if t.W == nil {
+		return errors.New("no terminal to notify in")
+	}
+	sequence, err := t.Sequence(n)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(t.W, sequence)
+	return err
+}
+
+// Sequence returns the escape sequence that raises the notification, for
+// programs that write to the terminal themselves
+func (t TerminalNotifier) Sequence(n Notification) (string, error) {
+	// The sequences end at the first control character
+	clean := func(text string) string {
+		return strings.Map(func(r rune) rune {
+			if r < 0x20 || r == 0x7f {
+				return ' '
+			}
+			return r
+		}, text)
+	}
+	var sequence string
+	switch t.OSC {
+	case 9:
+		sequence = fmt.Sprintf("\x1b]9;%s: %s\x07", clean(n.Title), clean(n.Body))
+	case 777:
+		// The title and body are separated by ;
+		sequence = fmt.Sprintf("\x1b]777;notify;%s;%s\x07", strings.ReplaceAll(clean(n.Title), ";", ","), clean(n.Body))
+	default:
+		return "", fmt.Errorf("unknown notification sequence OSC %d", t.OSC)
+	}
+	return sequence, nil
+}
+
+// WebhookNotifier posts notifications as JSON to a URL, e.g. for ntfy or a
+// chat bot: {"title": "...", "body": "..."}
+type WebhookNotifier struct {
+	URL string
+}
+
+func (w WebhookNotifier) Notify(n Notification) error {
+	data, err := json.Marshal(struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}{n.Title, n.Body})
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: %s replied %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+// RecordingNotifier keeps the notifications it's given instead of showing
+// them. Err, when set, is returned from every Notify so failures can be
+// simulated.
+type RecordingNotifier struct {
+	Err error
+
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (r *RecordingNotifier) Notify(n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications = append(r.notifications, n)
+	return r.Err
+}
+
+// Notifications returns the notifications recorded so far
+func (r *RecordingNotifier) Notifications() []Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Notification(nil), r.notifications...)
+}
+
+// powerShellQuote returns text as a single-quoted PowerShell string, in which
+// nothing is expanded. PowerShell also ends those strings at the typographic
+// single quotes, so they are doubled too.
+func powerShellQuote(text string) string {
+	return "'" + strings.NewReplacer("'", "''", "\u2018", "\u2018\u2018", "\u2019", "\u2019\u2019", "\u201a", "\u201a\u201a", "\u201b", "\u201b\u201b").Replace(text) + "'"
+}
+
+// appleScriptEscape escapes text for an AppleScript string literal
+func appleScriptEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
 }
 
-func PlayBeep() {
+// PlayBeep plays the system sound for a new phase
+func PlayBeep() error {
 	// Play a system beep sound
 	switch runtime.GOOS {
 	case "linux":
@@ -60,16 +236,18 @@ func PlayBeep() {
 		cmd := exec.Command("paplay", "/usr/share/sounds/freedesktop/stereo/complete.oga")
 		if err := cmd.Run(); err != nil {
 			// Fallback to beep command or speaker-test
-			exec.Command("speaker-test", "-t", "sine", "-f", "1000", "-l", "1").Run()
+			return runNotifier("speaker-test", "-t", "sine", "-f", "1000", "-l", "1")
 		}
+		return nil
 	case "darwin":
 		// macOS
-		exec.Command("afplay", "/System/Library/Sounds/Glass.aiff").Run()
+		return runNotifier("afplay", "/System/Library/Sounds/Glass.aiff")
 	case "windows":
 		// Windows - use rundll32 to play system sound
-		exec.Command("rundll32", "user32.dll,MessageBeep").Run()
+		return runNotifier("rundll32", "user32.dll,MessageBeep")
 	default:
 		// Fallback: print bell character
 		fmt.Print("\a")
+		return nil
 	}
 }
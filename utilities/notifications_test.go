@@ -0,0 +1,63 @@
+package utilities
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNotifierChainFallsBack(t *testing.T) {
+	broken := &RecordingNotifier{Err: errors.New("no desktop")}
+	working := &RecordingNotifier{}
+	unused := &RecordingNotifier{}
+	chain := NotifierChain{broken, working, unused}
+
+	n := Notification{Title: "Phase 2", Body: "375°"}
+	used, err := chain.Send(n)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if used != working {
+		t.Errorf("Send used %v, want the second notifier", used)
+	}
+	if got := broken.Notifications(); len(got) != 1 {
+		t.Errorf("failing notifier got %d notifications, want 1", len(got))
+	}
+	if got := working.Notifications(); len(got) != 1 || got[0].Title != n.Title {
+		t.Errorf("working notifier got %v, want %v", got, n)
+	}
+	if got := unused.Notifications(); len(got) != 0 {
+		t.Errorf("notifier after the working one got %v", got)
+	}
+}
+
+func TestNotifierChainAllFail(t *testing.T) {
+	chain := NotifierChain{
+		&RecordingNotifier{Err: errors.New("no desktop")},
+		&RecordingNotifier{Err: errors.New("no terminal")},
+	}
+	used, err := chain.Send(Notification{Title: "Phase 1"})
+	if used != nil {
+		t.Errorf("Send used %v, want none", used)
+	}
+	if err == nil || !strings.Contains(err.Error(), "no desktop") || !strings.Contains(err.Error(), "no terminal") {
+		t.Errorf("Send error = %v, want both failures", err)
+	}
+
+	if _, err := (NotifierChain{}).Send(Notification{Title: "Phase 1"}); err == nil {
+		t.Error("empty chain sent a notification")
+	}
+}
+
+func TestPowerShellQuote(t *testing.T) {
+	for text, want := range map[string]string{
+		"Phase 2":         "'Phase 2'",
+		`"$(rm -r ~)"`:    `'"$(rm -r ~)"'`,
+		"Ben's `profile`": "'Ben''s `profile`'",
+		"Ben’s timer":     "'Ben’’s timer'",
+	} {
+		if got := powerShellQuote(text); got != want {
+			t.Errorf("powerShellQuote(%q) = %s, want %s", text, got, want)
+		}
+	}
+}